@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"os"
@@ -10,13 +11,12 @@ import (
 	"github.com/deckhouse/dvp-csi-driver/internal/driver"
 	"github.com/deckhouse/dvp-csi-driver/internal/host"
 	"github.com/deckhouse/dvp-csi-driver/internal/logger"
+	"github.com/deckhouse/dvp-csi-driver/internal/tracing"
 )
 
 func main() {
-	hostCluster, err := host.NewClient()
-	if err != nil {
-		panic(err)
-	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
 	var csiEndpoint string
 	flag.StringVar(&csiEndpoint, "csi-endpoint", "", "CSI endpoint")
@@ -24,18 +24,75 @@ func main() {
 	flag.StringVar(&livenessEndpoint, "liveness-endpoint", "", "Liveness endpoint")
 	var isDebugMode bool
 	flag.BoolVar(&isDebugMode, "debug", false, "debug mode")
+	var modeName string
+	flag.StringVar(&modeName, "mode", string(driver.ModeAll), "driver mode: controller, node or all")
+	var maxVolumesPerNode int64
+	flag.Int64Var(&maxVolumesPerNode, "max-volumes-per-node", driver.DefaultMaxVolumesPerNode, "maximum number of disks a virtual machine can have, including boot and cloud-init ones")
+	var hostKubeconfigFile string
+	flag.StringVar(&hostKubeconfigFile, "host-kubeconfig-file", "", "host cluster kubeconfig file to watch for the credential rotation, the HOST_KUBECONFIG env is used if empty")
+	var isHostCachedReads bool
+	flag.BoolVar(&isHostCachedReads, "host-cached-reads", false, "read host objects from the informer cache")
+	var tracingEndpoint string
+	flag.StringVar(&tracingEndpoint, "tracing-endpoint", "", "host:port of the OTLP gRPC collector to export the traces to, disabled if empty")
+	var isTracingInsecure bool
+	flag.BoolVar(&isTracingInsecure, "tracing-insecure", false, "export the traces without TLS")
 	flag.Parse()
 
 	if csiEndpoint == "" {
 		panic(errors.New("CSI endpoint missed but required"))
 	}
 
+	mode, err := driver.ParseMode(modeName)
+	if err != nil {
+		panic(err)
+	}
+
+	if tracingEndpoint != "" {
+		shutdown, err := tracing.Setup(ctx, tracingEndpoint, isTracingInsecure)
+		if err != nil {
+			panic(err)
+		}
+
+		defer func() {
+			_ = shutdown(context.Background())
+		}()
+	}
+
 	var opts []logger.Option
 	if isDebugMode {
 		opts = append(opts, logger.NewDebugOption())
 	}
 
-	csi, err := driver.New(csiEndpoint, livenessEndpoint, hostCluster, logger.New(opts))
+	log := logger.New(opts...)
+
+	hostOpts := []host.Option{
+		host.NewLoggerOption(log),
+	}
+	if hostKubeconfigFile != "" {
+		hostOpts = append(hostOpts, host.NewKubeconfigFileOption(hostKubeconfigFile))
+	}
+
+	// The node mode requires no host cluster access: keep the credentials off the worker nodes
+	// unless they are given to report the topology.
+	var hostCluster *host.Client
+	if mode.IsController() {
+		if isHostCachedReads {
+			hostOpts = append(hostOpts, host.NewCachedReadsOption())
+		}
+
+		hostCluster, err = host.NewClient(ctx, hostOpts...)
+		if err != nil {
+			panic(err)
+		}
+	} else if hostKubeconfigFile != "" || os.Getenv("HOST_KUBECONFIG") != "" {
+		// The node reads the placement of its virtual machine for the topology only.
+		hostCluster, err = host.NewClient(ctx, append(hostOpts, host.NewWithoutInformersOption())...)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	csi, err := driver.New(mode, csiEndpoint, livenessEndpoint, hostCluster, log, driver.NewMaxVolumesPerNodeOption(maxVolumesPerNode))
 	if err != nil {
 		panic(err)
 	}
@@ -4,135 +4,548 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/google/uuid"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/wait"
 
 	"github.com/deckhouse/dvp-csi-driver/internal/host"
+	"github.com/deckhouse/dvp-csi-driver/internal/mounter"
 )
 
 var _ csi.ControllerServer = &Driver{}
 
+// publishContextSerialKey is a key of the disk serial in the publish context, used by the node to find the device.
+const publishContextSerialKey = "serial"
+
+// StorageClass parameters.
+const (
+	// storageClassParameter is a name of the host storage class for the disk.
+	storageClassParameter = "dvpStorageClass"
+	// sourceImageParameter is a name of the host VirtualMachineImage to populate the disk from.
+	sourceImageParameter = "dvpSourceImage"
+	// sourceClusterImageParameter is a name of the host ClusterVirtualMachineImage to populate the disk from.
+	sourceClusterImageParameter = "dvpSourceClusterImage"
+	// zoneStorageClassesParameter maps the zones to the host storage classes accessible from them only.
+	zoneStorageClassesParameter = "dvpZoneStorageClasses"
+	// multiNodeParameter allows attaching the disk to several nodes at once. The host storage class must
+	// provide shared block storage, such as Ceph RBD or replicated LINSTOR.
+	multiNodeParameter = "dvpMultiNode"
+)
+
 func (d *Driver) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequest) (*csi.CreateVolumeResponse, error) {
-	for _, capability := range req.GetVolumeCapabilities() {
-		switch capability.GetAccessMode().GetMode() {
-		case csi.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY,
-			csi.VolumeCapability_AccessMode_MULTI_NODE_SINGLE_WRITER,
-			csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER,
-			csi.VolumeCapability_AccessMode_UNKNOWN:
-			return nil, status.Error(codes.InvalidArgument, "not supported pvc access mode")
-		}
+	multiNode, err := isMultiNode(req.GetParameters())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	err = validateVolumeCapabilities(req.GetVolumeCapabilities(), multiNode)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	switch req.GetVolumeContentSource().GetType().(type) {
+	case nil:
+	case *csi.VolumeContentSource_Snapshot:
+		// The host disk cannot be restored from a snapshot without a snapshot resource in the host API.
+		return nil, errSnapshotsNotSupported
+	case *csi.VolumeContentSource_Volume:
+		// The host disk data source has no reference to another VirtualMachineDisk to clone from.
+		return nil, status.Error(codes.Unimplemented, "volume cloning is not supported: host disk data source cannot refer to a VirtualMachineDisk")
+	default:
+		return nil, status.Error(codes.InvalidArgument, "not supported volume content source")
 	}
 
 	var storageClass *string
-	dvpStorageClass, ok := req.GetParameters()["dvpStorageClass"]
+	dvpStorageClass, ok := req.GetParameters()[storageClassParameter]
 	if ok {
 		storageClass = &dvpStorageClass
 	}
 
-	disk, err := d.hostCluster.CreateDisk(ctx, req.Name, req.CapacityRange.RequiredBytes, storageClass)
+	source, err := diskSourceFromParameters(req.GetParameters())
+	if err != nil {
+		return nil, err
+	}
+
+	// The VolumeAttributesClass of the PVC overrides the storage class of the StorageClass.
+	vacStorageClass, ok, err := storageClassFromMutableParameters(req.GetMutableParameters())
+	if err != nil {
+		return nil, err
+	}
+
+	if ok {
+		storageClass = &vacStorageClass
+	}
+
+	zoneStorageClasses, err := parseZoneStorageClasses(req.GetParameters()[zoneStorageClassesParameter])
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if ok && len(zoneStorageClasses) > 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "%s mutable parameter cannot be used with %s parameter", storageClassParameter, zoneStorageClassesParameter)
+	}
+
+	// Without zonal storage classes, the disk is accessible from everywhere.
+	accessibleTopology := []*csi.Topology{}
+	if len(zoneStorageClasses) > 0 {
+		zone, ok := selectZone(req.GetAccessibilityRequirements(), zoneStorageClasses)
+		if !ok {
+			return nil, status.Error(codes.ResourceExhausted, "no host storage class for the zones of the topology requirements")
+		}
+
+		if zone != "" {
+			zoneStorageClass := zoneStorageClasses[zone]
+			storageClass = &zoneStorageClass
+			accessibleTopology = append(accessibleTopology, &csi.Topology{
+				Segments: map[string]string{
+					topologyZoneKey: zone,
+				},
+			})
+		}
+	}
+
+	ctx, done, ok := d.creations.Start(ctx, req.Name)
+	if !ok {
+		return nil, status.Error(codes.Aborted, "volume is already being created")
+	}
+	defer done()
+
+	// The lock is released before the creation is done, so that the aborting deletion can proceed.
+	unlock, err := d.volumeLocks.Lock(req.Name)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create disk: %w", err)
+		return nil, err
+	}
+	defer unlock()
+
+	disk, err := d.hostCluster.CreateDisk(ctx, req.Name, req.CapacityRange.RequiredBytes, storageClass, source)
+	if err != nil {
+		if isCreationAborted(ctx) {
+			return nil, status.Error(codes.Aborted, errCreationAborted.Error())
+		}
+
+		return nil, hostError("failed to create disk", err)
 	}
 
 	d.logger.Debug("Wait disk creation", "name", disk.Name)
 
 	err = d.hostCluster.WaitDiskCreation(ctx, disk.Name)
 	if err != nil {
-		return nil, err
+		if isCreationAborted(ctx) {
+			return nil, status.Error(codes.Aborted, errCreationAborted.Error())
+		}
+
+		return nil, hostError("failed to wait for disk creation", err)
+	}
+
+	if isCreationAborted(ctx) {
+		return nil, status.Error(codes.Aborted, errCreationAborted.Error())
+	}
+
+	// The volume context is passed to the publish calls to check the multi-node access there.
+	volumeContext := map[string]string{}
+	if multiNode {
+		volumeContext[multiNodeParameter] = strconv.FormatBool(multiNode)
 	}
 
 	return &csi.CreateVolumeResponse{
 		Volume: &csi.Volume{
 			CapacityBytes:      req.CapacityRange.RequiredBytes,
 			VolumeId:           req.Name,
-			VolumeContext:      map[string]string{},
+			VolumeContext:      volumeContext,
 			ContentSource:      req.VolumeContentSource,
-			AccessibleTopology: []*csi.Topology{},
+			AccessibleTopology: accessibleTopology,
 		},
 	}, nil
 }
 
-// DeleteVolume TODO: deleting in process of creation.
+// diskSourceFromParameters returns the host image to populate the disk from, or nil for a blank disk.
+func diskSourceFromParameters(parameters map[string]string) (*host.DiskSource, error) {
+	image := parameters[sourceImageParameter]
+	clusterImage := parameters[sourceClusterImageParameter]
+
+	switch {
+	case image != "" && clusterImage != "":
+		return nil, status.Errorf(codes.InvalidArgument, "only one of %s and %s parameters can be set", sourceImageParameter, sourceClusterImageParameter)
+	case image != "":
+		return &host.DiskSource{VirtualMachineImage: image}, nil
+	case clusterImage != "":
+		return &host.DiskSource{ClusterVirtualMachineImage: clusterImage}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// DeleteVolume aborts the creation of the volume, if any, and deletes the disk whatever phase it is in.
 func (d *Driver) DeleteVolume(ctx context.Context, req *csi.DeleteVolumeRequest) (*csi.DeleteVolumeResponse, error) {
+	err := d.creations.Abort(ctx, req.VolumeId)
+	if err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+
+	unlock, err := d.volumeLocks.Lock(req.VolumeId)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
 	disk, err := d.hostCluster.DeleteDisk(ctx, req.VolumeId)
 	if err != nil {
 		if errors.Is(err, host.ErrDiskAlreadyDeleted) {
 			return &csi.DeleteVolumeResponse{}, nil
 		}
 
-		return nil, fmt.Errorf("failed to delete disk: %w", err)
+		return nil, hostError("failed to delete disk", err)
 	}
 
 	d.logger.Debug("Wait disk deletion", "name", disk.Name)
 
 	err = d.hostCluster.WaitDiskDeletion(ctx, disk.Name)
 	if err != nil {
-		return nil, err
+		return nil, hostError("failed to wait for disk deletion", err)
 	}
 
 	return &csi.DeleteVolumeResponse{}, nil
 }
 
 func (d *Driver) ControllerPublishVolume(ctx context.Context, req *csi.ControllerPublishVolumeRequest) (*csi.ControllerPublishVolumeResponse, error) {
-	attachment, err := d.hostCluster.AttachDisk(ctx, req.VolumeId, req.NodeId)
+	multiNode, err := isMultiNode(req.GetVolumeContext())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	err = validateVolumeCapabilities([]*csi.VolumeCapability{req.GetVolumeCapability()}, multiNode)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create attachment: %w", err)
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// The disk published with a single-node access mode must not be attached to other nodes.
+	shared := isMultiNodeAccessMode(req.GetVolumeCapability().GetAccessMode().GetMode())
+
+	unlock, err := d.volumeLocks.Lock(req.VolumeId)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	attachment, err := d.hostCluster.AttachDisk(ctx, req.VolumeId, req.NodeId, shared)
+	if err != nil {
+		return nil, hostError("failed to create attachment", err)
 	}
 
 	d.logger.Debug("Wait disk attaching", "name", attachment.Name)
 
 	err = d.hostCluster.WaitDiskAttaching(ctx, attachment.Name)
 	if err != nil {
-		return nil, err
+		return nil, hostError("failed to wait for disk attaching", err)
 	}
 
 	return &csi.ControllerPublishVolumeResponse{
-		PublishContext: map[string]string{},
+		PublishContext: map[string]string{
+			publishContextSerialKey: attachment.Serial,
+		},
 	}, nil
 }
 
 func (d *Driver) ControllerUnpublishVolume(ctx context.Context, req *csi.ControllerUnpublishVolumeRequest) (*csi.ControllerUnpublishVolumeResponse, error) {
+	unlock, err := d.volumeLocks.Lock(req.VolumeId)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
 	detachment, err := d.hostCluster.DetachDisk(ctx, req.VolumeId, req.NodeId)
 	if err != nil {
 		if errors.Is(err, host.ErrAttachmentAlreadyDeleted) {
 			return &csi.ControllerUnpublishVolumeResponse{}, nil
 		}
 
-		return nil, fmt.Errorf("failed to delete attachment: %w", err)
+		return nil, hostError("failed to delete attachment", err)
 	}
 
 	d.logger.Debug("Wait disk detaching", "name", detachment.Name)
 
 	err = d.hostCluster.WaitDiskDetaching(ctx, detachment.Name)
 	if err != nil {
-		return nil, err
+		return nil, hostError("failed to wait for disk detaching", err)
 	}
 
 	return &csi.ControllerUnpublishVolumeResponse{}, nil
 }
 
-func (d *Driver) ValidateVolumeCapabilities(_ context.Context, _ *csi.ValidateVolumeCapabilitiesRequest) (*csi.ValidateVolumeCapabilitiesResponse, error) {
-	return nil, nil
+func (d *Driver) ValidateVolumeCapabilities(ctx context.Context, req *csi.ValidateVolumeCapabilitiesRequest) (*csi.ValidateVolumeCapabilitiesResponse, error) {
+	volumeID := req.GetVolumeId()
+	if len(volumeID) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Volume id cannot be empty")
+	}
+
+	if len(req.GetVolumeCapabilities()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Volume capabilities cannot be empty")
+	}
+
+	disk, err := d.hostCluster.GetDisk(ctx, volumeID)
+	if err != nil {
+		return nil, hostError("failed to get disk", err)
+	}
+
+	multiNode, err := isMultiNode(req.GetParameters())
+	if err == nil && !multiNode {
+		multiNode, err = isMultiNode(req.GetVolumeContext())
+	}
+	if err == nil {
+		err = validateVolumeCapabilities(req.GetVolumeCapabilities(), multiNode)
+	}
+	if err == nil {
+		err = validateDiskParameters(disk, req.GetParameters())
+	}
+	if err == nil {
+		err = validateDiskParameters(disk, req.GetVolumeContext())
+	}
+	if err != nil {
+		return &csi.ValidateVolumeCapabilitiesResponse{
+			Message: err.Error(),
+		}, nil
+	}
+
+	return &csi.ValidateVolumeCapabilitiesResponse{
+		Confirmed: &csi.ValidateVolumeCapabilitiesResponse_Confirmed{
+			VolumeContext:      req.GetVolumeContext(),
+			VolumeCapabilities: req.GetVolumeCapabilities(),
+			Parameters:         req.GetParameters(),
+		},
+	}, nil
+}
+
+// validateVolumeCapabilities checks that the driver supports the access modes and types of the capabilities.
+// The multi-node access modes are supported for the multi-node volumes only: writers share the raw block device,
+// and readers share the file system mounted read-only.
+func validateVolumeCapabilities(capabilities []*csi.VolumeCapability, multiNode bool) error {
+	for _, capability := range capabilities {
+		switch capability.GetAccessMode().GetMode() {
+		case csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER:
+			if !multiNode {
+				return fmt.Errorf("multi-node access mode requires the %s parameter", multiNodeParameter)
+			}
+
+			if capability.GetBlock() == nil {
+				return errors.New("multi-node multi-writer access mode is supported for block volumes only")
+			}
+		case csi.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY:
+			if !multiNode {
+				return fmt.Errorf("multi-node access mode requires the %s parameter", multiNodeParameter)
+			}
+
+			if capability.GetMount() == nil {
+				return errors.New("multi-node reader-only access mode is supported for file system volumes only")
+			}
+		case csi.VolumeCapability_AccessMode_MULTI_NODE_SINGLE_WRITER,
+			csi.VolumeCapability_AccessMode_UNKNOWN:
+			return errors.New("not supported pvc access mode")
+		}
+
+		switch capability.GetAccessType().(type) {
+		case *csi.VolumeCapability_Block:
+		case *csi.VolumeCapability_Mount:
+			if !mounter.IsFileSystemSupported(capability.GetMount().GetFsType()) {
+				return fmt.Errorf("not supported fs type: %s", capability.GetMount().GetFsType())
+			}
+		default:
+			return errors.New("not supported access type")
+		}
+	}
+
+	return nil
+}
+
+// isMultiNode reports whether the parameters allow attaching the disk to several nodes at once.
+func isMultiNode(parameters map[string]string) (bool, error) {
+	value, ok := parameters[multiNodeParameter]
+	if !ok {
+		return false, nil
+	}
+
+	multiNode, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s parameter: %w", multiNodeParameter, err)
+	}
+
+	return multiNode, nil
+}
+
+func isMultiNodeAccessMode(mode csi.VolumeCapability_AccessMode_Mode) bool {
+	switch mode {
+	case csi.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY,
+		csi.VolumeCapability_AccessMode_MULTI_NODE_SINGLE_WRITER,
+		csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER:
+		return true
+	default:
+		return false
+	}
 }
 
-func (d *Driver) ListVolumes(_ context.Context, _ *csi.ListVolumesRequest) (*csi.ListVolumesResponse, error) {
-	return nil, errors.New("not implemented")
+// validateDiskParameters checks that the StorageClass parameters match the properties of the existing disk.
+func validateDiskParameters(disk *host.Disk, parameters map[string]string) error {
+	zoneStorageClasses, err := parseZoneStorageClasses(parameters[zoneStorageClassesParameter])
+	if err != nil {
+		return err
+	}
+
+	storageClass, ok := parameters[storageClassParameter]
+	if ok && storageClass != disk.StorageClass && !isZoneStorageClass(zoneStorageClasses, disk.StorageClass) {
+		return fmt.Errorf("disk has storage class %q, but %q is required", disk.StorageClass, storageClass)
+	}
+
+	source, err := diskSourceFromParameters(parameters)
+	if err != nil {
+		return err
+	}
+
+	if source == nil {
+		return nil
+	}
+
+	if disk.Source == nil || *disk.Source != *source {
+		return errors.New("disk is not populated from the required image")
+	}
+
+	return nil
 }
 
-func (d *Driver) GetCapacity(_ context.Context, _ *csi.GetCapacityRequest) (*csi.GetCapacityResponse, error) {
-	return &csi.GetCapacityResponse{
-		AvailableCapacity: 10 * 1024 * 1024,
+func (d *Driver) ListVolumes(ctx context.Context, req *csi.ListVolumesRequest) (*csi.ListVolumesResponse, error) {
+	if req.GetMaxEntries() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "max entries cannot be negative")
+	}
+
+	disks, err := d.hostCluster.ListDisks(ctx, int64(req.GetMaxEntries()), req.GetStartingToken())
+	if err != nil {
+		return nil, hostError("failed to list disks", err)
+	}
+
+	machines, err := d.hostCluster.ListAttachedMachines(ctx)
+	if err != nil {
+		return nil, hostError("failed to list attachments", err)
+	}
+
+	entries := make([]*csi.ListVolumesResponse_Entry, len(disks.Disks))
+	for i, disk := range disks.Disks {
+		entries[i] = &csi.ListVolumesResponse_Entry{
+			Volume: &csi.Volume{
+				VolumeId:      disk.Name,
+				CapacityBytes: disk.Capacity.Value(),
+			},
+			Status: &csi.ListVolumesResponse_VolumeStatus{
+				PublishedNodeIds: machines[disk.Name],
+				VolumeCondition:  newVolumeCondition(disk.Condition),
+			},
+		}
+	}
+
+	return &csi.ListVolumesResponse{
+		Entries:   entries,
+		NextToken: disks.Continue,
 	}, nil
 }
 
+// volumeNamePrefix is a prefix of the volume names the external-provisioner generates by default.
+const volumeNamePrefix = "pvc-"
+
+// isVolumeName reports whether the disk name is generated by the external-provisioner for a volume.
+func isVolumeName(name string) bool {
+	id, ok := strings.CutPrefix(name, volumeNamePrefix)
+	if !ok {
+		return false
+	}
+
+	_, err := uuid.Parse(id)
+	return err == nil
+}
+
+// labelDisks labels the disks provisioned before the upgrade so that ListVolumes returns them,
+// retrying until it succeeds or the context is done.
+func (d *Driver) labelDisks(ctx context.Context) {
+	_ = wait.PollUntilContextCancel(ctx, defaultHealthCheckInterval, true, func(ctx context.Context) (bool, error) {
+		labeled, err := d.hostCluster.LabelDisks(ctx, isVolumeName)
+		if err != nil {
+			d.logger.Warn("Failed to label disks provisioned before", "err", err)
+			return false, nil
+		}
+
+		if labeled != 0 {
+			d.logger.Info("Labeled disks provisioned before", "count", labeled)
+		}
+
+		return true, nil
+	})
+}
+
+func (d *Driver) GetCapacity(ctx context.Context, req *csi.GetCapacityRequest) (*csi.GetCapacityResponse, error) {
+	multiNode, err := isMultiNode(req.GetParameters())
+	if err == nil {
+		err = validateVolumeCapabilities(req.GetVolumeCapabilities(), multiNode)
+	}
+	if err != nil {
+		// No volume with such capabilities can be created.
+		return &csi.GetCapacityResponse{}, nil
+	}
+
+	storageClass := req.GetParameters()[storageClassParameter]
+
+	zoneStorageClasses, err := parseZoneStorageClasses(req.GetParameters()[zoneStorageClassesParameter])
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// The capacity of the zone is of its own storage class, as the disks created in the zone get.
+	zone := req.GetAccessibleTopology().GetSegments()[topologyZoneKey]
+	if len(zoneStorageClasses) > 0 && zone != "" {
+		zoneStorageClass, ok := zoneStorageClasses[zone]
+		if !ok {
+			// No volume can be created in the zone.
+			return &csi.GetCapacityResponse{}, nil
+		}
+
+		storageClass = zoneStorageClass
+	}
+
+	capacity, err := d.hostCluster.GetCapacity(ctx, storageClass)
+	if err != nil {
+		return nil, hostError("failed to get capacity", err)
+	}
+
+	// Without quotas, nothing tells the storage left on the host, so the available capacity is not reported
+	// rather than reported unlimited: the scheduler would never notice a full host storage class then.
+	res := &csi.GetCapacityResponse{}
+
+	if capacity.Available != nil {
+		res.AvailableCapacity = capacity.Available.Value()
+	}
+
+	if capacity.MaximumVolumeSize != nil {
+		res.MaximumVolumeSize = &wrappers.Int64Value{
+			Value: capacity.MaximumVolumeSize.Value(),
+		}
+	}
+
+	return res, nil
+}
+
 func (d *Driver) ControllerGetCapabilities(_ context.Context, _ *csi.ControllerGetCapabilitiesRequest) (*csi.ControllerGetCapabilitiesResponse, error) {
 	capabilities := []csi.ControllerServiceCapability_RPC_Type{
 		csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME,
 		csi.ControllerServiceCapability_RPC_PUBLISH_UNPUBLISH_VOLUME,
 		csi.ControllerServiceCapability_RPC_EXPAND_VOLUME,
+		csi.ControllerServiceCapability_RPC_LIST_VOLUMES,
+		csi.ControllerServiceCapability_RPC_LIST_VOLUMES_PUBLISHED_NODES,
+		csi.ControllerServiceCapability_RPC_GET_VOLUME,
+		csi.ControllerServiceCapability_RPC_VOLUME_CONDITION,
+		csi.ControllerServiceCapability_RPC_GET_CAPACITY,
+		csi.ControllerServiceCapability_RPC_MODIFY_VOLUME,
 	}
 
 	csiCaps := make([]*csi.ControllerServiceCapability, len(capabilities))
@@ -151,16 +564,20 @@ func (d *Driver) ControllerGetCapabilities(_ context.Context, _ *csi.ControllerG
 	}, nil
 }
 
+// errSnapshotsNotSupported is returned by the snapshot RPCs: the host virtualization API
+// (core/v1alpha2) has no VirtualMachineDiskSnapshot resource to back a CSI snapshot with.
+var errSnapshotsNotSupported = status.Error(codes.Unimplemented, "snapshots are not supported: host virtualization API has no VirtualMachineDiskSnapshot resource")
+
 func (d *Driver) CreateSnapshot(_ context.Context, _ *csi.CreateSnapshotRequest) (*csi.CreateSnapshotResponse, error) {
-	return nil, errors.New("not implemented")
+	return nil, errSnapshotsNotSupported
 }
 
 func (d *Driver) DeleteSnapshot(_ context.Context, _ *csi.DeleteSnapshotRequest) (*csi.DeleteSnapshotResponse, error) {
-	return nil, errors.New("not implemented")
+	return nil, errSnapshotsNotSupported
 }
 
 func (d *Driver) ListSnapshots(_ context.Context, _ *csi.ListSnapshotsRequest) (*csi.ListSnapshotsResponse, error) {
-	return nil, errors.New("not implemented")
+	return nil, errSnapshotsNotSupported
 }
 
 // ResizeDelta TODO: for what?
@@ -172,14 +589,20 @@ func (d *Driver) ControllerExpandVolume(ctx context.Context, req *csi.Controller
 		return nil, status.Error(codes.InvalidArgument, "Volume id cannot be empty")
 	}
 
-	err := d.hostCluster.WaitDiskCreation(ctx, req.VolumeId)
+	unlock, err := d.volumeLocks.Lock(volumeID)
 	if err != nil {
 		return nil, err
 	}
+	defer unlock()
+
+	err = d.hostCluster.WaitDiskCreation(ctx, req.VolumeId)
+	if err != nil {
+		return nil, hostError("failed to wait for disk creation", err)
+	}
 
 	vmd, err := d.hostCluster.GetDisk(ctx, req.VolumeId)
 	if err != nil {
-		return nil, err
+		return nil, hostError("failed to get disk", err)
 	}
 
 	requiredCapacity := resource.NewQuantity(req.CapacityRange.GetRequiredBytes(), resource.BinarySI)
@@ -196,7 +619,7 @@ func (d *Driver) ControllerExpandVolume(ctx context.Context, req *csi.Controller
 
 	err = d.hostCluster.UpdateDiskCapacity(ctx, req.VolumeId, requiredCapacity)
 	if err != nil {
-		return nil, err
+		return nil, hostError("failed to update disk capacity", err)
 	}
 
 	return &csi.ControllerExpandVolumeResponse{
@@ -205,10 +628,97 @@ func (d *Driver) ControllerExpandVolume(ctx context.Context, req *csi.Controller
 	}, nil
 }
 
-func (d *Driver) ControllerGetVolume(_ context.Context, _ *csi.ControllerGetVolumeRequest) (*csi.ControllerGetVolumeResponse, error) {
-	return nil, errors.New("not implemented")
+func (d *Driver) ControllerGetVolume(ctx context.Context, req *csi.ControllerGetVolumeRequest) (*csi.ControllerGetVolumeResponse, error) {
+	volumeID := req.GetVolumeId()
+	if len(volumeID) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Volume id cannot be empty")
+	}
+
+	disk, err := d.hostCluster.GetDisk(ctx, volumeID)
+	if err != nil {
+		return nil, hostError("failed to get disk", err)
+	}
+
+	machines, err := d.hostCluster.ListAttachedMachines(ctx)
+	if err != nil {
+		return nil, hostError("failed to list attachments", err)
+	}
+
+	return &csi.ControllerGetVolumeResponse{
+		Volume: &csi.Volume{
+			VolumeId:      disk.Name,
+			CapacityBytes: disk.Capacity.Value(),
+		},
+		Status: &csi.ControllerGetVolumeResponse_VolumeStatus{
+			PublishedNodeIds: machines[disk.Name],
+			VolumeCondition:  newVolumeCondition(disk.Condition),
+		},
+	}, nil
+}
+
+func newVolumeCondition(condition host.DiskCondition) *csi.VolumeCondition {
+	return &csi.VolumeCondition{
+		Abnormal: condition.Abnormal,
+		Message:  condition.Message,
+	}
 }
 
-func (d *Driver) ControllerModifyVolume(_ context.Context, _ *csi.ControllerModifyVolumeRequest) (*csi.ControllerModifyVolumeResponse, error) {
-	return nil, errors.New("not implemented")
+// ControllerModifyVolume applies the mutable parameters of a VolumeAttributesClass to the host disk.
+// The host exposes no IOPS or throughput settings, so only the storage class can be changed.
+func (d *Driver) ControllerModifyVolume(ctx context.Context, req *csi.ControllerModifyVolumeRequest) (*csi.ControllerModifyVolumeResponse, error) {
+	volumeID := req.GetVolumeId()
+	if len(volumeID) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Volume id cannot be empty")
+	}
+
+	storageClass, ok, err := storageClassFromMutableParameters(req.GetMutableParameters())
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return &csi.ControllerModifyVolumeResponse{}, nil
+	}
+
+	unlock, err := d.volumeLocks.Lock(volumeID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	err = d.hostCluster.UpdateDiskStorageClass(ctx, volumeID, storageClass)
+	if err != nil {
+		return nil, hostError("failed to update disk storage class", err)
+	}
+
+	d.logger.Debug("Wait disk updating", "name", volumeID)
+
+	err = d.hostCluster.WaitDiskUpdating(ctx, volumeID, storageClass, func(phase, progress string) {
+		d.logger.Info("Disk is updating", "name", volumeID, "phase", phase, "progress", progress)
+	})
+	if err != nil {
+		return nil, hostError("failed to wait for disk updating", err)
+	}
+
+	return &csi.ControllerModifyVolumeResponse{}, nil
+}
+
+// storageClassFromMutableParameters returns the host storage class set by the VolumeAttributesClass, if any.
+func storageClassFromMutableParameters(parameters map[string]string) (string, bool, error) {
+	for parameter := range parameters {
+		if parameter != storageClassParameter {
+			return "", false, status.Errorf(codes.InvalidArgument, "not supported mutable parameter: %s", parameter)
+		}
+	}
+
+	storageClass, ok := parameters[storageClassParameter]
+	if !ok {
+		return "", false, nil
+	}
+
+	if storageClass == "" {
+		return "", false, status.Errorf(codes.InvalidArgument, "%s parameter cannot be empty", storageClassParameter)
+	}
+
+	return storageClass, true, nil
 }
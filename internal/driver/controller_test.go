@@ -0,0 +1,257 @@
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/deckhouse/virtualization/api/core/v1alpha2"
+)
+
+// pagingHost serves the disk lists in pages with the offset of the next page as a continue token,
+// and no attachments.
+func pagingHost(t *testing.T, names ...string) http.Handler {
+	t.Helper()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var list interface{}
+
+		switch r.URL.Path {
+		case "/apis/" + v1alpha2.SchemeGroupVersion.String() + "/namespaces/test/" + v1alpha2.VMDResource:
+			offset := 0
+			if token := r.URL.Query().Get("continue"); token != "" {
+				var err error
+				offset, err = strconv.Atoi(token)
+				if err != nil {
+					writeStatus(w, metav1.StatusReasonExpired, http.StatusGone)
+					return
+				}
+			}
+
+			limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+			page := names[min(offset, len(names)):]
+			vmds := &v1alpha2.VirtualMachineDiskList{
+				TypeMeta: metav1.TypeMeta{Kind: "VirtualMachineDiskList", APIVersion: v1alpha2.SchemeGroupVersion.String()},
+			}
+			if limit > 0 && len(page) > limit {
+				page = page[:limit]
+				vmds.Continue = strconv.Itoa(offset + limit)
+			}
+
+			for _, name := range page {
+				vmds.Items = append(vmds.Items, v1alpha2.VirtualMachineDisk{
+					ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test"},
+				})
+			}
+
+			list = vmds
+		case "/apis/" + v1alpha2.SchemeGroupVersion.String() + "/namespaces/test/" + v1alpha2.VMBDAResource:
+			list = &v1alpha2.VirtualMachineBlockDeviceAttachmentList{
+				TypeMeta: metav1.TypeMeta{Kind: "VirtualMachineBlockDeviceAttachmentList", APIVersion: v1alpha2.SchemeGroupVersion.String()},
+			}
+		default:
+			writeStatus(w, metav1.StatusReasonNotFound, http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(list)
+	})
+}
+
+func writeStatus(w http.ResponseWriter, reason metav1.StatusReason, code int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(&metav1.Status{
+		TypeMeta: metav1.TypeMeta{Kind: "Status", APIVersion: "v1"},
+		Status:   metav1.StatusFailure,
+		Reason:   reason,
+		Code:     int32(code),
+	})
+}
+
+func TestListVolumesPages(t *testing.T) {
+	d := newTestDriver(newTestHostClient(t, pagingHost(t, "pvc-1", "pvc-2", "pvc-3")))
+
+	var volumes []string
+	var pages int
+	req := &csi.ListVolumesRequest{MaxEntries: 2}
+	for {
+		resp, err := d.ListVolumes(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages++
+
+		if len(resp.Entries) > 2 {
+			t.Fatalf("expected at most 2 entries, got %d", len(resp.Entries))
+		}
+
+		for _, entry := range resp.Entries {
+			volumes = append(volumes, entry.Volume.VolumeId)
+		}
+
+		if resp.NextToken == "" {
+			break
+		}
+
+		if pages > 2 {
+			t.Fatalf("expected 2 pages, got more: %v", volumes)
+		}
+
+		req.StartingToken = resp.NextToken
+	}
+
+	if pages != 2 || len(volumes) != 3 || volumes[0] != "pvc-1" || volumes[2] != "pvc-3" {
+		t.Fatalf("expected pvc-1, pvc-2 and pvc-3 on 2 pages, got %v on %d", volumes, pages)
+	}
+}
+
+func TestListVolumesInvalidStartingToken(t *testing.T) {
+	d := newTestDriver(newTestHostClient(t, pagingHost(t, "pvc-1")))
+
+	_, err := d.ListVolumes(context.Background(), &csi.ListVolumesRequest{
+		MaxEntries:    2,
+		StartingToken: "expired",
+	})
+	expectAborted(t, err)
+}
+
+func TestListVolumesNegativeMaxEntries(t *testing.T) {
+	d := newTestDriver(nil)
+
+	_, err := d.ListVolumes(context.Background(), &csi.ListVolumesRequest{MaxEntries: -1})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected invalid argument, got %v", err)
+	}
+}
+
+func TestStorageClassFromMutableParameters(t *testing.T) {
+	tests := []struct {
+		name         string
+		parameters   map[string]string
+		storageClass string
+		ok           bool
+		invalid      bool
+	}{
+		{
+			name: "no parameters",
+		},
+		{
+			name:         "storage class",
+			parameters:   map[string]string{storageClassParameter: "fast"},
+			storageClass: "fast",
+			ok:           true,
+		},
+		{
+			name:       "empty storage class",
+			parameters: map[string]string{storageClassParameter: ""},
+			invalid:    true,
+		},
+		{
+			name:       "not supported parameter",
+			parameters: map[string]string{storageClassParameter: "fast", "iops": "1000"},
+			invalid:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storageClass, ok, err := storageClassFromMutableParameters(tt.parameters)
+			if tt.invalid {
+				if status.Code(err) != codes.InvalidArgument {
+					t.Fatalf("expected invalid argument, got %v", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if storageClass != tt.storageClass || ok != tt.ok {
+				t.Fatalf("expected %q, %t, got %q, %t", tt.storageClass, tt.ok, storageClass, ok)
+			}
+		})
+	}
+}
+
+func TestMutableParametersValidation(t *testing.T) {
+	// The invalid requests are rejected before any request to the host.
+	d := newTestDriver(nil)
+
+	zonal := newCreateVolumeRequest()
+	zonal.Parameters = map[string]string{zoneStorageClassesParameter: "zone-a=fast"}
+	zonal.MutableParameters = map[string]string{storageClassParameter: "slow"}
+
+	notSupported := newCreateVolumeRequest()
+	notSupported.MutableParameters = map[string]string{"iops": "1000"}
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{
+			name: "CreateVolume with zone storage classes",
+			call: func() error {
+				_, err := d.CreateVolume(context.Background(), zonal)
+				return err
+			},
+		},
+		{
+			name: "CreateVolume with not supported parameter",
+			call: func() error {
+				_, err := d.CreateVolume(context.Background(), notSupported)
+				return err
+			},
+		},
+		{
+			name: "ControllerModifyVolume with not supported parameter",
+			call: func() error {
+				_, err := d.ControllerModifyVolume(context.Background(), &csi.ControllerModifyVolumeRequest{
+					VolumeId:          testVolumeID,
+					MutableParameters: map[string]string{"throughput": "100"},
+				})
+				return err
+			},
+		},
+		{
+			name: "ControllerModifyVolume without volume id",
+			call: func() error {
+				_, err := d.ControllerModifyVolume(context.Background(), &csi.ControllerModifyVolumeRequest{
+					MutableParameters: map[string]string{storageClassParameter: "fast"},
+				})
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			if status.Code(err) != codes.InvalidArgument {
+				t.Fatalf("expected invalid argument, got %v", err)
+			}
+		})
+	}
+}
+
+func TestControllerModifyVolumeWithoutStorageClass(t *testing.T) {
+	d := newTestDriver(nil)
+
+	_, err := d.ControllerModifyVolume(context.Background(), &csi.ControllerModifyVolumeRequest{
+		VolumeId: testVolumeID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,78 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var errCreationAborted = errors.New("volume deleted while being created")
+
+// creations tracks the volumes being created so that their deletion can abort the creation.
+type creations struct {
+	mu        sync.Mutex
+	inProcess map[string]*creation
+}
+
+type creation struct {
+	cancel context.CancelCauseFunc
+	done   chan struct{}
+}
+
+func newCreations() *creations {
+	return &creations{
+		inProcess: make(map[string]*creation),
+	}
+}
+
+// Start registers the creation of the volume and returns its context, which is canceled on abort,
+// and a function to call when the creation is finished. It returns false if the volume is already being created.
+func (c *creations) Start(ctx context.Context, volumeID string) (context.Context, func(), bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.inProcess[volumeID]; ok {
+		return nil, nil, false
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	cr := &creation{
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	c.inProcess[volumeID] = cr
+
+	return ctx, func() {
+		c.mu.Lock()
+		delete(c.inProcess, volumeID)
+		c.mu.Unlock()
+
+		cancel(nil)
+		close(cr.done)
+	}, true
+}
+
+// Abort cancels the creation of the volume, if any, and waits for it to finish.
+func (c *creations) Abort(ctx context.Context, volumeID string) error {
+	c.mu.Lock()
+	cr, ok := c.inProcess[volumeID]
+	c.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	cr.cancel(errCreationAborted)
+
+	select {
+	case <-cr.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// isCreationAborted reports whether the creation with the context was aborted.
+func isCreationAborted(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), errCreationAborted)
+}
@@ -0,0 +1,161 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCreationsAbortWithoutCreation(t *testing.T) {
+	c := newCreations()
+
+	err := c.Abort(context.Background(), testVolumeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreationsStartTwice(t *testing.T) {
+	c := newCreations()
+
+	_, done, ok := c.Start(context.Background(), testVolumeID)
+	if !ok {
+		t.Fatal("expected the creation to start")
+	}
+
+	_, _, ok = c.Start(context.Background(), testVolumeID)
+	if ok {
+		t.Fatal("expected the second creation of the volume not to start")
+	}
+
+	_, doneOther, ok := c.Start(context.Background(), "pvc-other")
+	if !ok {
+		t.Fatal("expected the creation of another volume to start")
+	}
+	doneOther()
+
+	done()
+
+	_, done, ok = c.Start(context.Background(), testVolumeID)
+	if !ok {
+		t.Fatal("expected the creation to start again once done")
+	}
+	done()
+}
+
+func TestCreationsAbortWaitsForCreation(t *testing.T) {
+	c := newCreations()
+
+	ctx, done, ok := c.Start(context.Background(), testVolumeID)
+	if !ok {
+		t.Fatal("expected the creation to start")
+	}
+
+	// The creation is blocked in a wait until its context is canceled.
+	created := callAsync(func() error {
+		defer done()
+
+		<-ctx.Done()
+		if !isCreationAborted(ctx) {
+			return errors.New("expected the creation to be aborted")
+		}
+
+		return nil
+	})
+
+	aborted := callAsync(func() error {
+		return c.Abort(context.Background(), testVolumeID)
+	})
+
+	err := expectCallResult(t, created)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = expectCallResult(t, aborted)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(c.inProcess) != 0 {
+		t.Fatal("expected no creations in process")
+	}
+}
+
+func TestCreationsAbortCanceled(t *testing.T) {
+	c := newCreations()
+
+	_, done, ok := c.Start(context.Background(), testVolumeID)
+	if !ok {
+		t.Fatal("expected the creation to start")
+	}
+	defer done()
+
+	// The creation does not finish in time.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := c.Abort(ctx, testVolumeID)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestCreationsDoneIsNotAborted(t *testing.T) {
+	c := newCreations()
+
+	ctx, done, ok := c.Start(context.Background(), testVolumeID)
+	if !ok {
+		t.Fatal("expected the creation to start")
+	}
+
+	done()
+
+	if isCreationAborted(ctx) {
+		t.Fatal("expected the finished creation not to be aborted")
+	}
+}
+
+func TestCreateVolumeAlreadyBeingCreated(t *testing.T) {
+	d := newTestDriver(nil)
+
+	_, done, ok := d.creations.Start(context.Background(), testVolumeID)
+	if !ok {
+		t.Fatal("expected the creation to start")
+	}
+	defer done()
+
+	_, err := d.CreateVolume(context.Background(), newCreateVolumeRequest())
+	expectAborted(t, err)
+}
+
+func TestDeleteVolumeAbortsCreateVolume(t *testing.T) {
+	hostServer := newBlockingHost(t)
+	d := newTestDriver(newTestHostClient(t, hostServer))
+
+	created := callAsync(func() error {
+		_, err := d.CreateVolume(context.Background(), newCreateVolumeRequest())
+		return err
+	})
+	hostServer.WaitRequested(t)
+
+	deleted := callAsync(func() error {
+		_, err := d.DeleteVolume(context.Background(), &csi.DeleteVolumeRequest{VolumeId: testVolumeID})
+		return err
+	})
+
+	// The creation is canceled and releases the lock to the deletion.
+	err := expectCallResult(t, created)
+	if status.Code(err) != codes.Aborted || status.Convert(err).Message() != errCreationAborted.Error() {
+		t.Fatalf("expected the creation to be aborted by the deletion, got %v", err)
+	}
+
+	hostServer.Release()
+	expectNotAborted(t, expectCallResult(t, deleted))
+	expectUnlocked(t, d.volumeLocks, testVolumeID)
+}
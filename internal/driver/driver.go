@@ -12,51 +12,193 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"time"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 
+	"github.com/deckhouse/dvp-csi-driver/internal/health"
 	"github.com/deckhouse/dvp-csi-driver/internal/host"
+	"github.com/deckhouse/dvp-csi-driver/internal/metrics"
 	"github.com/deckhouse/dvp-csi-driver/internal/mounter"
+	"github.com/deckhouse/dvp-csi-driver/internal/tracing"
 )
 
+// Mode is a set of the CSI services served by the driver.
+type Mode string
+
+const (
+	// ModeController serves Identity and Controller services, it requires the host cluster access.
+	ModeController Mode = "controller"
+	// ModeNode serves Identity and Node services, it requires no host cluster access.
+	ModeNode Mode = "node"
+	// ModeAll serves all services.
+	ModeAll Mode = "all"
+)
+
+// ParseMode returns the mode by its name.
+func ParseMode(mode string) (Mode, error) {
+	switch m := Mode(mode); m {
+	case ModeController, ModeNode, ModeAll:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unknown mode %q: expected one of %s, %s, %s", mode, ModeController, ModeNode, ModeAll)
+	}
+}
+
+// IsController reports whether the mode serves the Controller service.
+func (m Mode) IsController() bool {
+	return m == ModeController || m == ModeAll
+}
+
+// IsNode reports whether the mode serves the Node service.
+func (m Mode) IsNode() bool {
+	return m == ModeNode || m == ModeAll
+}
+
 type Driver struct {
+	mode             Mode
 	nodeName         string
 	csiEndpoint      string
 	livenessEndpoint string
 
 	hostCluster *host.Client
-	grpc        *grpc.Server
-	http        *http.Server
-	mounter     *mounter.Mounter
+	// guestCluster is used by the node to read its own labels, nil if unavailable.
+	guestCluster kubernetes.Interface
+	grpc         *grpc.Server
+	http         *http.Server
+	mounter      *mounter.Mounter
+	creations    *creations
+	// volumeLocks serializes the controller calls on the same volume.
+	volumeLocks *locks
+	// stagingLocks serializes the node calls on the same volume.
+	stagingLocks *locks
+	// targetLocks serializes the node calls on the same target path.
+	targetLocks *locks
+	health      *health.Checker
+	// cancel stops the background work of the driver.
+	cancel context.CancelFunc
+
+	maxVolumesPerNode int64
 
 	logger *slog.Logger
 }
 
+// DefaultMaxVolumesPerNode is a default maximum number of disks a virtual machine can have.
+const DefaultMaxVolumesPerNode = 16
+
+// defaultHealthCheckInterval is an interval to check the prerequisites of the driver.
+const defaultHealthCheckInterval = time.Minute
+
 // New returns a CSI plugin that contains the necessary gRPC
 // interfaces to interact with Kubernetes over unix domain sockets for
-// managaing  disks
-func New(csiEndpoint, livenessEndpoint string, hostCluster *host.Client, logger *slog.Logger) (*Driver, error) {
-	nodeName := os.Getenv("NODE_NAME")
-	if nodeName == "" {
-		return nil, errors.New("node name env not found")
-	}
-
-	logger = logger.WithGroup("driver").With("host-id", nodeName)
-
-	return &Driver{
-		nodeName:         nodeName,
-		csiEndpoint:      csiEndpoint,
-		livenessEndpoint: livenessEndpoint,
-		hostCluster:      hostCluster,
-		mounter:          mounter.New(logger),
-		logger:           logger,
-	}, nil
+// managaing  disks. The host cluster client is required for the controller mode only.
+func New(mode Mode, csiEndpoint, livenessEndpoint string, hostCluster *host.Client, logger *slog.Logger, options ...Option) (*Driver, error) {
+	if mode.IsController() && hostCluster == nil {
+		return nil, errors.New("host cluster client is required in controller mode")
+	}
+
+	d := &Driver{
+		mode:              mode,
+		csiEndpoint:       csiEndpoint,
+		livenessEndpoint:  livenessEndpoint,
+		hostCluster:       hostCluster,
+		creations:         newCreations(),
+		volumeLocks:       newLocks(),
+		stagingLocks:      newLocks(),
+		targetLocks:       newLocks(),
+		maxVolumesPerNode: DefaultMaxVolumesPerNode,
+	}
+
+	for _, option := range options {
+		switch o := option.(type) {
+		case *MaxVolumesPerNodeOption:
+			d.maxVolumesPerNode = o.Value
+		default:
+		}
+	}
+
+	logger = logger.WithGroup("driver").With("mode", mode)
+
+	if mode.IsNode() {
+		d.nodeName = os.Getenv("NODE_NAME")
+		if d.nodeName == "" {
+			return nil, errors.New("node name env not found")
+		}
+
+		logger = logger.With("host-id", d.nodeName)
+		d.mounter = mounter.New(logger)
+
+		config, err := rest.InClusterConfig()
+		if err != nil {
+			logger.Warn("Guest cluster is unavailable: node labels are ignored", "err", err)
+		} else {
+			d.guestCluster, err = kubernetes.NewForConfig(config)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	d.logger = logger
+	d.health = health.NewChecker(defaultHealthCheckInterval, logger, d.healthChecks()...)
+
+	return d, nil
+}
+
+// healthChecks returns the checks of the prerequisites of the services the driver serves.
+func (d *Driver) healthChecks() []health.Check {
+	var checks []health.Check
+
+	if d.hostCluster != nil {
+		checks = append(checks, health.Check{
+			Name: "host-credentials",
+			Check: func(_ context.Context) error {
+				return d.hostCluster.CheckCredentials()
+			},
+		})
+	}
+
+	if d.mode.IsController() {
+		checks = append(checks, health.Check{
+			Name:  "host-access",
+			Check: d.hostCluster.CheckAccess,
+		})
+	}
+
+	if d.mode.IsNode() {
+		checks = append(checks,
+			health.Check{
+				Name:  "node-tools",
+				Check: d.mounter.CheckTools,
+			},
+			health.Check{
+				Name:  "node-devices",
+				Check: d.mounter.CheckDevices,
+			},
+		)
+	}
+
+	return checks
 }
 
 func (d *Driver) Start() error {
 	d.logger.Info("Start driver")
 
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+
+	d.health.Start(ctx)
+
+	if d.mode.IsController() {
+		go d.labelDisks(ctx)
+	}
+
 	err := d.startCSIEndpoint()
 	if err != nil {
 		return err
@@ -79,6 +221,10 @@ func (d *Driver) Stop() error {
 
 	d.grpc.GracefulStop()
 
+	if d.cancel != nil {
+		d.cancel()
+	}
+
 	if d.http != nil {
 		err := d.http.Shutdown(context.Background())
 		if err != nil {
@@ -120,10 +266,14 @@ func (d *Driver) startCSIEndpoint() error {
 		return fmt.Errorf("failed to listen: %w", err)
 	}
 
-	d.grpc = grpc.NewServer(grpc.UnaryInterceptor(d.logInterceptor))
+	d.grpc = grpc.NewServer(grpc.ChainUnaryInterceptor(d.tracingInterceptor, d.metricsInterceptor, d.logInterceptor))
 	csi.RegisterIdentityServer(d.grpc, d)
-	csi.RegisterControllerServer(d.grpc, d)
-	csi.RegisterNodeServer(d.grpc, d)
+	if d.mode.IsController() {
+		csi.RegisterControllerServer(d.grpc, d)
+	}
+	if d.mode.IsNode() {
+		csi.RegisterNodeServer(d.grpc, d)
+	}
 
 	go func() {
 		err := d.grpc.Serve(grpcListener)
@@ -142,9 +292,28 @@ func (d *Driver) startLivenessEndpoint() error {
 	}
 
 	mux := http.NewServeMux()
+	// The process is alive while it serves, whatever the state of the dependencies reported by /readyz.
 	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		report := d.health.Report()
+
+		w.Header().Set("Content-Type", "application/json")
+		if !report.Healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+
+		err := json.NewEncoder(w).Encode(report)
+		if err != nil {
+			d.logger.Error("Failed to write health report", "err", err)
+		}
+	})
+	mux.Handle("/metrics", metrics.Handler())
+
+	d.http = &http.Server{
+		Handler: mux,
+	}
 
 	go func() {
 		err := d.http.Serve(httpListener)
@@ -153,11 +322,39 @@ func (d *Driver) startLivenessEndpoint() error {
 		}
 	}()
 
-	d.http = &http.Server{
-		Handler: mux,
+	return nil
+}
+
+// volumeRequest is a CSI request on a volume.
+type volumeRequest interface {
+	GetVolumeId() string
+}
+
+func (d *Driver) tracingInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	attrs := []attribute.KeyValue{
+		semconv.RPCSystemKey.String("grpc"),
+		semconv.RPCMethod(path.Base(info.FullMethod)),
+	}
+	if req, ok := req.(volumeRequest); ok && req.GetVolumeId() != "" {
+		attrs = append(attrs, attribute.String("csi.volume_id", req.GetVolumeId()))
 	}
 
-	return nil
+	ctx, span := tracing.Start(ctx, info.FullMethod, attrs...)
+
+	res, err := handler(ctx, req)
+	span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(status.Code(err))))
+	tracing.End(span, err)
+
+	return res, err
+}
+
+func (d *Driver) metricsInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	done := metrics.StartOperation(path.Base(info.FullMethod))
+
+	res, err := handler(ctx, req)
+	done(err)
+
+	return res, err
 }
 
 func (d *Driver) logInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
@@ -0,0 +1,35 @@
+package driver
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	"github.com/deckhouse/dvp-csi-driver/internal/mounter"
+	"github.com/deckhouse/dvp-csi-driver/internal/tracing/tracingtest"
+)
+
+func TestTracingInterceptorSpans(t *testing.T) {
+	spans := tracingtest.Record(t)
+
+	d := &Driver{
+		mounter: mounter.New(slog.Default()),
+	}
+
+	const method = "/csi.v1.Node/NodeUnpublishVolume"
+	info := &grpc.UnaryServerInfo{FullMethod: method}
+
+	_, err := d.tracingInterceptor(context.Background(), nil, info, func(ctx context.Context, _ interface{}) (interface{}, error) {
+		// Nothing is mounted to the target, but the operation is traced anyway.
+		return nil, d.mounter.Unmount(ctx, filepath.Join(t.TempDir(), "target"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorded := spans()
+	tracingtest.ExpectChild(t, tracingtest.FindSpan(t, recorded, "mounter.unmount"), tracingtest.FindSpan(t, recorded, method))
+}
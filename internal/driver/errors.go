@@ -0,0 +1,84 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/deckhouse/dvp-csi-driver/internal/host"
+)
+
+// hostError translates the error of the host cluster to the gRPC status the sidecars decide
+// whether and how to retry upon. The message of the host is preserved.
+func hostError(message string, err error) error {
+	return status.Errorf(hostErrorCode(err), "%s: %v", message, err)
+}
+
+func hostErrorCode(err error) codes.Code {
+	if s, ok := status.FromError(err); ok {
+		return s.Code()
+	}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	case errors.Is(err, host.ErrDiskNotFound),
+		errors.Is(err, host.ErrAttachmentNotFound),
+		errors.Is(err, host.ErrMachineNotFound):
+		return codes.NotFound
+	case errors.Is(err, host.ErrDiskAttachedToAnotherMachine),
+		errors.Is(err, host.ErrDiskNotShareable):
+		return codes.FailedPrecondition
+	case errors.Is(err, host.ErrInvalidContinueToken):
+		return codes.Aborted
+	case errors.Is(err, host.ErrUnauthenticated):
+		return codes.Unauthenticated
+	case errors.Is(err, host.ErrAccessDenied):
+		return codes.PermissionDenied
+	case isAdmissionDenial(err):
+		// The request itself is wrong for the host, a retry would be denied too.
+		return codes.InvalidArgument
+	case k8serrors.IsForbidden(err) && isQuotaExceeded(err):
+		return codes.ResourceExhausted
+	case k8serrors.IsNotFound(err):
+		return codes.NotFound
+	case k8serrors.IsConflict(err), k8serrors.IsAlreadyExists(err):
+		return codes.Aborted
+	case k8serrors.IsTimeout(err), k8serrors.IsServerTimeout(err):
+		return codes.DeadlineExceeded
+	case k8serrors.IsTooManyRequests(err), k8serrors.IsServiceUnavailable(err):
+		return codes.Unavailable
+	case k8serrors.IsUnauthorized(err):
+		return codes.Unauthenticated
+	case k8serrors.IsForbidden(err):
+		return codes.PermissionDenied
+	case k8serrors.IsInvalid(err), k8serrors.IsBadRequest(err):
+		return codes.InvalidArgument
+	default:
+		return codes.Internal
+	}
+}
+
+// isAdmissionDenial reports whether the request was denied by an admission webhook or policy of the host cluster.
+func isAdmissionDenial(err error) bool {
+	var statusErr k8serrors.APIStatus
+	if !errors.As(err, &statusErr) {
+		return false
+	}
+
+	message := statusErr.Status().Message
+
+	return strings.Contains(message, "admission webhook") && strings.Contains(message, "denied the request") ||
+		strings.Contains(message, "ValidatingAdmissionPolicy") && strings.Contains(message, "denied request")
+}
+
+// isQuotaExceeded reports whether the request was forbidden by a ResourceQuota of the host namespace.
+func isQuotaExceeded(err error) bool {
+	return strings.Contains(err.Error(), "exceeded quota")
+}
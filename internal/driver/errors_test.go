@@ -0,0 +1,151 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/deckhouse/dvp-csi-driver/internal/host"
+	"github.com/deckhouse/virtualization/api/core/v1alpha2"
+)
+
+func TestHostErrorCode(t *testing.T) {
+	disks := v1alpha2.SchemeGroupVersion.WithResource(v1alpha2.VMDResource).GroupResource()
+	disk := schema.GroupKind{Group: v1alpha2.SchemeGroupVersion.Group, Kind: v1alpha2.VMDKind}
+
+	quotaExceeded := k8serrors.NewForbidden(disks, testVolumeID,
+		errors.New("exceeded quota: storage, requested: requests.storage=10Gi, used: requests.storage=95Gi, limited: requests.storage=100Gi"))
+	webhookDenial := k8serrors.NewForbidden(disks, testVolumeID,
+		errors.New(`admission webhook "vmd.virtualization-controller.validate.d8-virtualization" denied the request: the disk size cannot be decreased`))
+	policyDenial := k8serrors.NewInvalid(disk, testVolumeID, field.ErrorList{
+		field.Forbidden(field.NewPath("spec"), "ValidatingAdmissionPolicy 'disks' with binding 'disks' denied request"),
+	})
+
+	tests := []struct {
+		name string
+		err  error
+		code codes.Code
+	}{
+		{name: "status", err: status.Error(codes.Aborted, "aborted"), code: codes.Aborted},
+		{name: "deadline exceeded", err: context.DeadlineExceeded, code: codes.DeadlineExceeded},
+		{name: "canceled", err: context.Canceled, code: codes.Canceled},
+		{name: "disk not found", err: host.ErrDiskNotFound, code: codes.NotFound},
+		{name: "attachment not found", err: host.ErrAttachmentNotFound, code: codes.NotFound},
+		{name: "machine not found", err: host.ErrMachineNotFound, code: codes.NotFound},
+		{name: "disk attached to another machine", err: host.ErrDiskAttachedToAnotherMachine, code: codes.FailedPrecondition},
+		{name: "disk not shareable", err: host.ErrDiskNotShareable, code: codes.FailedPrecondition},
+		{name: "invalid continue token", err: host.ErrInvalidContinueToken, code: codes.Aborted},
+		{name: "unauthenticated", err: host.ErrUnauthenticated, code: codes.Unauthenticated},
+		{name: "access denied", err: host.ErrAccessDenied, code: codes.PermissionDenied},
+		{name: "not found", err: k8serrors.NewNotFound(disks, testVolumeID), code: codes.NotFound},
+		{name: "conflict", err: k8serrors.NewConflict(disks, testVolumeID, errors.New("object has been modified")), code: codes.Aborted},
+		{name: "already exists", err: k8serrors.NewAlreadyExists(disks, testVolumeID), code: codes.Aborted},
+		{name: "timeout", err: k8serrors.NewTimeoutError("timed out", 1), code: codes.DeadlineExceeded},
+		{name: "server timeout", err: k8serrors.NewServerTimeout(disks, "create", 1), code: codes.DeadlineExceeded},
+		{name: "too many requests", err: k8serrors.NewTooManyRequests("too many requests", 1), code: codes.Unavailable},
+		{name: "service unavailable", err: k8serrors.NewServiceUnavailable("unavailable"), code: codes.Unavailable},
+		{name: "unauthorized", err: k8serrors.NewUnauthorized("unauthorized"), code: codes.Unauthenticated},
+		{name: "forbidden", err: k8serrors.NewForbidden(disks, testVolumeID, errors.New("no access")), code: codes.PermissionDenied},
+		{name: "quota exceeded", err: quotaExceeded, code: codes.ResourceExhausted},
+		{name: "webhook denial", err: webhookDenial, code: codes.InvalidArgument},
+		{name: "policy denial", err: policyDenial, code: codes.InvalidArgument},
+		{name: "invalid", err: k8serrors.NewInvalid(disk, testVolumeID, nil), code: codes.InvalidArgument},
+		{name: "bad request", err: k8serrors.NewBadRequest("bad request"), code: codes.InvalidArgument},
+		{name: "wrapped sentinel", err: fmt.Errorf("failed to get disk: %w", host.ErrDiskNotFound), code: codes.NotFound},
+		{name: "wrapped status error", err: fmt.Errorf("failed to create disk: %w", quotaExceeded), code: codes.ResourceExhausted},
+		{name: "wrapped context error", err: fmt.Errorf("failed to wait: %w", context.DeadlineExceeded), code: codes.DeadlineExceeded},
+		{name: "internal", err: k8serrors.NewInternalError(errors.New("internal")), code: codes.Internal},
+		{name: "unknown", err: errors.New("unknown"), code: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := hostErrorCode(tt.err)
+			if code != tt.code {
+				t.Fatalf("expected %s, got %s", tt.code, code)
+			}
+
+			err := hostError("failed", tt.err)
+			if status.Code(err) != tt.code {
+				t.Fatalf("expected the status %s, got %v", tt.code, err)
+			}
+		})
+	}
+}
+
+func TestIsQuotaExceeded(t *testing.T) {
+	disks := v1alpha2.SchemeGroupVersion.WithResource(v1alpha2.VMDResource).GroupResource()
+
+	tests := []struct {
+		name     string
+		err      error
+		exceeded bool
+	}{
+		{
+			name:     "quota exceeded",
+			err:      k8serrors.NewForbidden(disks, testVolumeID, errors.New("exceeded quota: storage")),
+			exceeded: true,
+		},
+		{
+			name: "forbidden",
+			err:  k8serrors.NewForbidden(disks, testVolumeID, errors.New("no access")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if isQuotaExceeded(tt.err) != tt.exceeded {
+				t.Fatalf("expected %t for %v", tt.exceeded, tt.err)
+			}
+		})
+	}
+}
+
+func TestIsAdmissionDenial(t *testing.T) {
+	disks := v1alpha2.SchemeGroupVersion.WithResource(v1alpha2.VMDResource).GroupResource()
+
+	tests := []struct {
+		name   string
+		err    error
+		denial bool
+	}{
+		{
+			name:   "webhook denial",
+			err:    k8serrors.NewForbidden(disks, testVolumeID, errors.New(`admission webhook "vmd.example.com" denied the request: invalid size`)),
+			denial: true,
+		},
+		{
+			name:   "policy denial",
+			err:    k8serrors.NewForbidden(disks, testVolumeID, errors.New("ValidatingAdmissionPolicy 'disks' with binding 'disks' denied request: invalid size")),
+			denial: true,
+		},
+		{
+			name:   "wrapped webhook denial",
+			err:    fmt.Errorf("failed to create disk: %w", k8serrors.NewBadRequest(`admission webhook "vmd.example.com" denied the request: invalid size`)),
+			denial: true,
+		},
+		{
+			name: "forbidden",
+			err:  k8serrors.NewForbidden(disks, testVolumeID, errors.New("no access")),
+		},
+		{
+			name: "not a status error",
+			err:  errors.New(`admission webhook "vmd.example.com" denied the request`),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if isAdmissionDenial(tt.err) != tt.denial {
+				t.Fatalf("expected %t for %v", tt.denial, tt.err)
+			}
+		})
+	}
+}
@@ -5,6 +5,8 @@ import (
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/golang/protobuf/ptypes/wrappers"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 var _ csi.IdentityServer = &Driver{}
@@ -28,15 +30,19 @@ func (d *Driver) GetPluginInfo(_ context.Context, _ *csi.GetPluginInfoRequest) (
 func (d *Driver) GetPluginCapabilities(_ context.Context, _ *csi.GetPluginCapabilitiesRequest) (*csi.GetPluginCapabilitiesResponse, error) {
 	d.logger.Info("Got GetPluginCapabilities request")
 
-	return &csi.GetPluginCapabilitiesResponse{
-		Capabilities: []*csi.PluginCapability{
-			{
-				Type: &csi.PluginCapability_Service_{
-					Service: &csi.PluginCapability_Service{
-						Type: csi.PluginCapability_Service_CONTROLLER_SERVICE,
-					},
+	var capabilities []*csi.PluginCapability
+	if d.mode.IsController() {
+		capabilities = append(capabilities, &csi.PluginCapability{
+			Type: &csi.PluginCapability_Service_{
+				Service: &csi.PluginCapability_Service{
+					Type: csi.PluginCapability_Service_CONTROLLER_SERVICE,
 				},
 			},
+		})
+	}
+
+	return &csi.GetPluginCapabilitiesResponse{
+		Capabilities: append(capabilities, []*csi.PluginCapability{
 			{
 				Type: &csi.PluginCapability_Service_{
 					Service: &csi.PluginCapability_Service{
@@ -58,17 +64,30 @@ func (d *Driver) GetPluginCapabilities(_ context.Context, _ *csi.GetPluginCapabi
 					},
 				},
 			},
-		},
+		}...),
 	}, nil
 }
 
-// Probe returns the health and readiness of the plugin
+// Probe returns the readiness of the plugin: it is not ready while any check of /readyz fails,
+// and fails with the Unauthenticated code while the host cluster rejects the credentials.
 func (d *Driver) Probe(_ context.Context, _ *csi.ProbeRequest) (*csi.ProbeResponse, error) {
 	d.logger.Info("Got Probe request")
 
+	if d.hostCluster != nil {
+		err := d.hostCluster.CheckCredentials()
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+	}
+
+	err := d.health.Err()
+	if err != nil {
+		d.logger.Warn("Driver is not ready", "err", err)
+	}
+
 	return &csi.ProbeResponse{
 		Ready: &wrappers.BoolValue{
-			Value: true,
+			Value: err == nil,
 		},
 	}, nil
 }
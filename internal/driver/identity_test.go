@@ -0,0 +1,76 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/deckhouse/dvp-csi-driver/internal/health"
+)
+
+func TestProbe(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		ready bool
+	}{
+		{
+			name:  "checks pass",
+			ready: true,
+		},
+		{
+			name: "check fails",
+			err:  errors.New("failed"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDriver(nil)
+			d.health = health.NewChecker(time.Hour, slog.Default(), health.Check{
+				Name: "test",
+				Check: func(_ context.Context) error {
+					return tt.err
+				},
+			})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			t.Cleanup(cancel)
+			d.health.Start(ctx)
+
+			resp, err := d.Probe(context.Background(), &csi.ProbeRequest{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.Ready.GetValue() != tt.ready {
+				t.Fatalf("expected ready %t, got %t", tt.ready, resp.Ready.GetValue())
+			}
+		})
+	}
+}
+
+func TestProbeUnauthenticated(t *testing.T) {
+	d := newTestDriver(newTestHostClient(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		writeStatus(w, metav1.StatusReasonUnauthorized, http.StatusUnauthorized)
+	})))
+	d.health = health.NewChecker(time.Hour, slog.Default())
+
+	_, err := d.ControllerGetVolume(context.Background(), &csi.ControllerGetVolumeRequest{VolumeId: testVolumeID})
+	if err == nil {
+		t.Fatal("expected the host cluster to reject the credentials")
+	}
+
+	_, err = d.Probe(context.Background(), &csi.ProbeRequest{})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected unauthenticated, got %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package driver
+
+import (
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errOperationInProgress is returned for a call conflicting with the one in progress, so that the CO retries it later.
+var errOperationInProgress = status.Error(codes.Aborted, "operation already in progress")
+
+// locks serializes the calls on the same volume or target path.
+type locks struct {
+	mu   sync.Mutex
+	held map[string]struct{}
+}
+
+func newLocks() *locks {
+	return &locks{
+		held: make(map[string]struct{}),
+	}
+}
+
+// Lock locks the key without waiting and returns a function to unlock it.
+// It returns errOperationInProgress if the key is already locked.
+func (l *locks) Lock(key string) (func(), error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.held[key]; ok {
+		return nil, errOperationInProgress
+	}
+
+	l.held[key] = struct{}{}
+
+	return func() {
+		l.mu.Lock()
+		delete(l.held, key)
+		l.mu.Unlock()
+	}, nil
+}
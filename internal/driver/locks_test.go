@@ -0,0 +1,469 @@
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/deckhouse/dvp-csi-driver/internal/host"
+	"github.com/deckhouse/dvp-csi-driver/internal/mounter"
+	"github.com/deckhouse/virtualization/api/core/v1alpha2"
+)
+
+const testVolumeID = "pvc-0f8a1c2e-3b4d-4e5f-8a9b-0c1d2e3f4a5b"
+
+// newTestHostClient returns a host client without informers talking to the API server handled by the handler.
+func newTestHostClient(t *testing.T, handler http.Handler) *host.Client {
+	t.Helper()
+
+	server := httptest.NewServer(withDiscovery(handler))
+	t.Cleanup(server.Close)
+
+	kubeconfig := fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: host
+  cluster:
+    server: %s
+users:
+- name: csi
+  user:
+    token: token
+contexts:
+- name: host
+  context:
+    cluster: host
+    user: csi
+current-context: host
+`, server.URL)
+
+	kubeconfigFile := filepath.Join(t.TempDir(), "kubeconfig")
+	err := os.WriteFile(kubeconfigFile, []byte(kubeconfig), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("HOST_NAMESPACE", "test")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	hostCluster, err := host.NewClient(ctx,
+		host.NewWithoutInformersOption(),
+		host.NewKubeconfigFileOption(kubeconfigFile),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return hostCluster
+}
+
+// withDiscovery serves the discovery of the host objects, so that only the requests for the objects reach the handler.
+func withDiscovery(handler http.Handler) http.Handler {
+	resources := &metav1.APIResourceList{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "APIResourceList",
+			APIVersion: "v1",
+		},
+		GroupVersion: v1alpha2.SchemeGroupVersion.String(),
+		APIResources: []metav1.APIResource{
+			{Name: v1alpha2.VMDResource, Namespaced: true, Kind: v1alpha2.VMDKind},
+			{Name: v1alpha2.VMBDAResource, Namespaced: true, Kind: v1alpha2.VMBDAKind},
+		},
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/apis/"+v1alpha2.SchemeGroupVersion.String() {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resources)
+	})
+}
+
+// failingHost responds to every request with an error.
+func failingHost() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	})
+}
+
+// blockingHost holds every request until released or canceled by the client, then responds with an error.
+// A request received is reported to the requested channel, so that the call is known to hold its lock.
+type blockingHost struct {
+	requested chan struct{}
+	release   chan struct{}
+}
+
+func newBlockingHost(t *testing.T) *blockingHost {
+	t.Helper()
+
+	h := &blockingHost{
+		requested: make(chan struct{}, 1),
+		release:   make(chan struct{}),
+	}
+	t.Cleanup(h.Release)
+
+	return h
+}
+
+func (h *blockingHost) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	select {
+	case h.requested <- struct{}{}:
+	default:
+	}
+
+	select {
+	case <-h.release:
+	case <-r.Context().Done():
+	}
+
+	http.Error(w, "unavailable", http.StatusServiceUnavailable)
+}
+
+func (h *blockingHost) Release() {
+	select {
+	case <-h.release:
+	default:
+		close(h.release)
+	}
+}
+
+func (h *blockingHost) WaitRequested(t *testing.T) {
+	t.Helper()
+
+	select {
+	case <-h.requested:
+	case <-time.After(5 * time.Second):
+		t.Fatal("host has not been requested")
+	}
+}
+
+func newTestDriver(hostCluster *host.Client) *Driver {
+	return &Driver{
+		hostCluster:  hostCluster,
+		mounter:      mounter.New(slog.Default()),
+		creations:    newCreations(),
+		volumeLocks:  newLocks(),
+		stagingLocks: newLocks(),
+		targetLocks:  newLocks(),
+		logger:       slog.Default(),
+	}
+}
+
+// callAsync runs the call in the background and returns the channel receiving its error.
+func callAsync(call func() error) <-chan error {
+	result := make(chan error, 1)
+	go func() {
+		result <- call()
+	}()
+
+	return result
+}
+
+func expectCallResult(t *testing.T, result <-chan error) error {
+	t.Helper()
+
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("call has not returned")
+		return nil
+	}
+}
+
+func expectAborted(t *testing.T, err error) {
+	t.Helper()
+
+	if status.Code(err) != codes.Aborted {
+		t.Fatalf("expected the call to be aborted, got %v", err)
+	}
+}
+
+func expectNotAborted(t *testing.T, err error) {
+	t.Helper()
+
+	if status.Code(err) == codes.Aborted {
+		t.Fatalf("expected the call not to conflict, got %v", err)
+	}
+}
+
+func expectUnlocked(t *testing.T, l *locks, key string) {
+	t.Helper()
+
+	unlock, err := l.Lock(key)
+	if err != nil {
+		t.Fatalf("expected %s to be unlocked: %v", key, err)
+	}
+	unlock()
+}
+
+func newCreateVolumeRequest() *csi.CreateVolumeRequest {
+	return &csi.CreateVolumeRequest{
+		Name: testVolumeID,
+		CapacityRange: &csi.CapacityRange{
+			RequiredBytes: 1 << 30,
+		},
+		VolumeCapabilities: []*csi.VolumeCapability{newMountCapability()},
+	}
+}
+
+func newMountCapability() *csi.VolumeCapability {
+	return &csi.VolumeCapability{
+		AccessType: &csi.VolumeCapability_Mount{
+			Mount: &csi.VolumeCapability_MountVolume{},
+		},
+		AccessMode: &csi.VolumeCapability_AccessMode{
+			Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+		},
+	}
+}
+
+func newControllerPublishVolumeRequest(nodeID string) *csi.ControllerPublishVolumeRequest {
+	return &csi.ControllerPublishVolumeRequest{
+		VolumeId:         testVolumeID,
+		NodeId:           nodeID,
+		VolumeCapability: newMountCapability(),
+	}
+}
+
+func TestLocks(t *testing.T) {
+	l := newLocks()
+
+	unlock, err := l.Lock("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = l.Lock("a")
+	expectAborted(t, err)
+
+	expectUnlocked(t, l, "b")
+
+	unlock()
+	expectUnlocked(t, l, "a")
+}
+
+func TestCreateVolumeConflictsWithDeleteVolume(t *testing.T) {
+	hostServer := newBlockingHost(t)
+	d := newTestDriver(newTestHostClient(t, hostServer))
+
+	deleted := callAsync(func() error {
+		_, err := d.DeleteVolume(context.Background(), &csi.DeleteVolumeRequest{VolumeId: testVolumeID})
+		return err
+	})
+	hostServer.WaitRequested(t)
+
+	_, err := d.CreateVolume(context.Background(), newCreateVolumeRequest())
+	expectAborted(t, err)
+
+	hostServer.Release()
+	expectNotAborted(t, expectCallResult(t, deleted))
+	expectUnlocked(t, d.volumeLocks, testVolumeID)
+}
+
+func TestControllerPublishVolumeConflictsWithVolumeCalls(t *testing.T) {
+	hostServer := newBlockingHost(t)
+	d := newTestDriver(newTestHostClient(t, hostServer))
+
+	published := callAsync(func() error {
+		_, err := d.ControllerPublishVolume(context.Background(), newControllerPublishVolumeRequest("node-1"))
+		return err
+	})
+	hostServer.WaitRequested(t)
+
+	_, err := d.ControllerUnpublishVolume(context.Background(), &csi.ControllerUnpublishVolumeRequest{
+		VolumeId: testVolumeID,
+		NodeId:   "node-1",
+	})
+	expectAborted(t, err)
+
+	_, err = d.ControllerPublishVolume(context.Background(), newControllerPublishVolumeRequest("node-2"))
+	expectAborted(t, err)
+
+	_, err = d.DeleteVolume(context.Background(), &csi.DeleteVolumeRequest{VolumeId: testVolumeID})
+	expectAborted(t, err)
+
+	_, err = d.ControllerExpandVolume(context.Background(), &csi.ControllerExpandVolumeRequest{
+		VolumeId: testVolumeID,
+		CapacityRange: &csi.CapacityRange{
+			RequiredBytes: 2 << 30,
+		},
+	})
+	expectAborted(t, err)
+
+	hostServer.Release()
+	expectNotAborted(t, expectCallResult(t, published))
+	expectUnlocked(t, d.volumeLocks, testVolumeID)
+}
+
+func TestDeleteVolumeConflictsWithControllerPublishVolume(t *testing.T) {
+	hostServer := newBlockingHost(t)
+	d := newTestDriver(newTestHostClient(t, hostServer))
+
+	deleted := callAsync(func() error {
+		_, err := d.DeleteVolume(context.Background(), &csi.DeleteVolumeRequest{VolumeId: testVolumeID})
+		return err
+	})
+	hostServer.WaitRequested(t)
+
+	_, err := d.ControllerPublishVolume(context.Background(), newControllerPublishVolumeRequest("node-1"))
+	expectAborted(t, err)
+
+	hostServer.Release()
+	expectNotAborted(t, expectCallResult(t, deleted))
+	expectUnlocked(t, d.volumeLocks, testVolumeID)
+}
+
+func TestNodePublishVolumeConflictsWithNodeUnpublishVolume(t *testing.T) {
+	d := newTestDriver(nil)
+
+	target := filepath.Join(t.TempDir(), "target")
+
+	// A call on the target is in progress.
+	unlock, err := d.targetLocks.Lock(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = d.NodePublishVolume(context.Background(), &csi.NodePublishVolumeRequest{
+		VolumeId:          testVolumeID,
+		StagingTargetPath: filepath.Join(t.TempDir(), "staging"),
+		TargetPath:        target,
+		VolumeCapability:  newMountCapability(),
+	})
+	expectAborted(t, err)
+
+	_, err = d.NodeUnpublishVolume(context.Background(), &csi.NodeUnpublishVolumeRequest{
+		VolumeId:   testVolumeID,
+		TargetPath: target,
+	})
+	expectAborted(t, err)
+
+	// The volume is not locked by its target path: nothing is mounted to the other target.
+	_, err = d.NodeUnpublishVolume(context.Background(), &csi.NodeUnpublishVolumeRequest{
+		VolumeId:   testVolumeID,
+		TargetPath: filepath.Join(t.TempDir(), "other"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unlock()
+
+	_, err = d.NodeUnpublishVolume(context.Background(), &csi.NodeUnpublishVolumeRequest{
+		VolumeId:   testVolumeID,
+		TargetPath: target,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLocksReleasedOnError(t *testing.T) {
+	const nodeID = "node-1"
+
+	target := filepath.Join(t.TempDir(), "target")
+
+	tests := []struct {
+		name  string
+		call  func(d *Driver) error
+		locks func(d *Driver) *locks
+		key   string
+	}{
+		{
+			name: "CreateVolume",
+			call: func(d *Driver) error {
+				_, err := d.CreateVolume(context.Background(), newCreateVolumeRequest())
+				return err
+			},
+			locks: func(d *Driver) *locks { return d.volumeLocks },
+			key:   testVolumeID,
+		},
+		{
+			name: "DeleteVolume",
+			call: func(d *Driver) error {
+				_, err := d.DeleteVolume(context.Background(), &csi.DeleteVolumeRequest{VolumeId: testVolumeID})
+				return err
+			},
+			locks: func(d *Driver) *locks { return d.volumeLocks },
+			key:   testVolumeID,
+		},
+		{
+			name: "ControllerExpandVolume",
+			call: func(d *Driver) error {
+				_, err := d.ControllerExpandVolume(context.Background(), &csi.ControllerExpandVolumeRequest{
+					VolumeId: testVolumeID,
+				})
+				return err
+			},
+			locks: func(d *Driver) *locks { return d.volumeLocks },
+			key:   testVolumeID,
+		},
+		{
+			name: "ControllerPublishVolume",
+			call: func(d *Driver) error {
+				_, err := d.ControllerPublishVolume(context.Background(), newControllerPublishVolumeRequest(nodeID))
+				return err
+			},
+			locks: func(d *Driver) *locks { return d.volumeLocks },
+			key:   testVolumeID,
+		},
+		{
+			name: "ControllerUnpublishVolume",
+			call: func(d *Driver) error {
+				_, err := d.ControllerUnpublishVolume(context.Background(), &csi.ControllerUnpublishVolumeRequest{
+					VolumeId: testVolumeID,
+					NodeId:   nodeID,
+				})
+				return err
+			},
+			locks: func(d *Driver) *locks { return d.volumeLocks },
+			key:   testVolumeID,
+		},
+		{
+			name: "NodePublishVolume",
+			call: func(d *Driver) error {
+				// The file system volume cannot be published without the staging path.
+				_, err := d.NodePublishVolume(context.Background(), &csi.NodePublishVolumeRequest{
+					VolumeId:         testVolumeID,
+					TargetPath:       target,
+					VolumeCapability: newMountCapability(),
+				})
+				return err
+			},
+			locks: func(d *Driver) *locks { return d.targetLocks },
+			key:   target,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDriver(newTestHostClient(t, failingHost()))
+
+			err := tt.call(d)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			expectNotAborted(t, err)
+
+			expectUnlocked(t, tt.locks(d), tt.key)
+		})
+	}
+}
@@ -2,69 +2,279 @@ package driver
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/google/uuid"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/deckhouse/dvp-csi-driver/internal/mounter"
 )
 
 var _ csi.NodeServer = &Driver{}
 
-func (d *Driver) NodeStageVolume(_ context.Context, _ *csi.NodeStageVolumeRequest) (*csi.NodeStageVolumeResponse, error) {
+// NodeStageVolume formats the file system volume, if needed, and mounts it to the staging path once per node.
+// The block volumes are bind-mounted right to the publish targets, so there is nothing to stage.
+func (d *Driver) NodeStageVolume(ctx context.Context, req *csi.NodeStageVolumeRequest) (*csi.NodeStageVolumeResponse, error) {
+	if len(req.GetVolumeId()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "volume id cannot be empty")
+	}
+	if len(req.GetStagingTargetPath()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "staging target path cannot be empty")
+	}
+	if req.GetVolumeCapability() == nil {
+		return nil, status.Error(codes.InvalidArgument, "volume capability cannot be empty")
+	}
+
+	mnt := req.GetVolumeCapability().GetMount()
+	if mnt == nil {
+		return &csi.NodeStageVolumeResponse{}, nil
+	}
+
+	unlock, err := d.stagingLocks.Lock(req.GetVolumeId())
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	blockDevicePath, err := d.mounter.GetBlockDevicePath(ctx, diskSerial(req.GetVolumeId(), req.GetPublishContext()))
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+
+	mountOptions := mnt.GetMountFlags()
+	if req.GetVolumeCapability().GetAccessMode().GetMode() == csi.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY {
+		// The file system shared by several nodes must never be written: neither formatted nor recovered.
+		mountOptions = append(mountOptions, mounter.ReadOnlyMountOptions(mnt.GetFsType())...)
+	}
+
+	d.logger.Info("Staging the volume file system", "source", blockDevicePath, "target", req.GetStagingTargetPath(), "fs-type", mnt.GetFsType(), "opts", mountOptions)
+	err = d.mounter.MountFileSystem(ctx, blockDevicePath, req.GetStagingTargetPath(), mnt.GetFsType(), mountOptions...)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
 	return &csi.NodeStageVolumeResponse{}, nil
 }
 
-func (d *Driver) NodeUnstageVolume(_ context.Context, _ *csi.NodeUnstageVolumeRequest) (*csi.NodeUnstageVolumeResponse, error) {
+func (d *Driver) NodeUnstageVolume(ctx context.Context, req *csi.NodeUnstageVolumeRequest) (*csi.NodeUnstageVolumeResponse, error) {
+	if len(req.GetVolumeId()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "volume id cannot be empty")
+	}
+	if len(req.GetStagingTargetPath()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "staging target path cannot be empty")
+	}
+
+	unlock, err := d.stagingLocks.Lock(req.GetVolumeId())
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	err = d.mounter.Unmount(ctx, req.GetStagingTargetPath())
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
 	return &csi.NodeUnstageVolumeResponse{}, nil
 }
 
-func (d *Driver) NodePublishVolume(_ context.Context, req *csi.NodePublishVolumeRequest) (*csi.NodePublishVolumeResponse, error) {
-	var mountOptions []string
-	if req.GetReadonly() {
-		mountOptions = append(mountOptions, "ro")
+// NodePublishVolume bind-mounts the staging path of the file system volume, or the device of the block volume, to the target path.
+func (d *Driver) NodePublishVolume(ctx context.Context, req *csi.NodePublishVolumeRequest) (*csi.NodePublishVolumeResponse, error) {
+	if len(req.GetVolumeId()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "volume id cannot be empty")
 	}
-
-	mnt := req.GetVolumeCapability().GetMount()
-	if mnt != nil {
-		mountOptions = append(mountOptions, mnt.GetMountFlags()...)
+	if len(req.GetTargetPath()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "target path cannot be empty")
 	}
 
-	blockDevicePath, err := d.mounter.GetBlockDevicePathByID(req.VolumeId)
+	unlock, err := d.targetLocks.Lock(req.GetTargetPath())
 	if err != nil {
-		return nil, status.Error(codes.NotFound, err.Error())
+		return nil, err
+	}
+	defer unlock()
+
+	var mountOptions []string
+	if req.GetReadonly() || isReadOnlyAccessMode(req.GetVolumeCapability().GetAccessMode().GetMode()) {
+		mountOptions = append(mountOptions, "ro")
 	}
 
 	switch req.GetVolumeCapability().GetAccessType().(type) {
 	case *csi.VolumeCapability_Block:
+		var blockDevicePath string
+		blockDevicePath, err = d.mounter.GetBlockDevicePath(ctx, diskSerial(req.GetVolumeId(), req.GetPublishContext()))
+		if err != nil {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+
 		d.logger.Info("Mounting the volume block", "source", blockDevicePath, "target", req.GetTargetPath(), "opts", mountOptions)
-		err = d.mounter.MountBlockDevice(blockDevicePath, req.GetTargetPath(), mountOptions...)
+		err = d.mounter.MountBlockDevice(ctx, blockDevicePath, req.GetTargetPath(), mountOptions...)
 	case *csi.VolumeCapability_Mount:
-		d.logger.Info("Mounting the volume file system", "source", blockDevicePath, "target", req.GetTargetPath(), "fs-type", mnt.GetFsType(), "opts", mountOptions)
-		err = d.mounter.MountFileSystem(blockDevicePath, req.GetTargetPath(), mnt.GetFsType(), mountOptions...)
+		if len(req.GetStagingTargetPath()) == 0 {
+			return nil, status.Error(codes.FailedPrecondition, "staging target path cannot be empty")
+		}
+
+		d.logger.Info("Mounting the volume file system", "source", req.GetStagingTargetPath(), "target", req.GetTargetPath(), "opts", mountOptions)
+		err = d.mounter.MountDirectory(ctx, req.GetStagingTargetPath(), req.GetTargetPath(), mountOptions...)
 	default:
 		return nil, status.Error(codes.InvalidArgument, "Unknown access type")
 	}
 	if err != nil {
-		return nil, err
+		return nil, status.Error(codes.Internal, err.Error())
 	}
 
 	return &csi.NodePublishVolumeResponse{}, nil
 }
 
-func (d *Driver) NodeUnpublishVolume(_ context.Context, req *csi.NodeUnpublishVolumeRequest) (*csi.NodeUnpublishVolumeResponse, error) {
-	err := d.mounter.Unmount(req.GetTargetPath())
+func isReadOnlyAccessMode(mode csi.VolumeCapability_AccessMode_Mode) bool {
+	return mode == csi.VolumeCapability_AccessMode_SINGLE_NODE_READER_ONLY ||
+		mode == csi.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY
+}
+
+// isVolumeSerial reports whether the disk serial is a volume name, as the host hotplugs the disks with,
+// possibly truncated by virtio-blk.
+func isVolumeSerial(serial string) bool {
+	if isVolumeName(serial) {
+		return true
+	}
+
+	id, ok := strings.CutPrefix(serial, volumeNamePrefix)
+	if !ok || len(serial) != mounter.VirtioSerialMaxLength {
+		return false
+	}
+
+	// The truncated id is completed to check the format of the rest.
+	const nilUUID = "00000000-0000-0000-0000-000000000000"
+	_, err := uuid.Parse(id + nilUUID[len(id):])
+
+	return err == nil
+}
+
+// diskSerial returns the disk serial from the publish context. Volumes published without it
+// are attached with the volume id as a serial.
+func diskSerial(volumeID string, publishContext map[string]string) string {
+	serial, ok := publishContext[publishContextSerialKey]
+	if !ok || serial == "" {
+		return volumeID
+	}
+
+	return serial
+}
+
+func (d *Driver) NodeUnpublishVolume(ctx context.Context, req *csi.NodeUnpublishVolumeRequest) (*csi.NodeUnpublishVolumeResponse, error) {
+	if len(req.GetVolumeId()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "volume id cannot be empty")
+	}
+	if len(req.GetTargetPath()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "target path cannot be empty")
+	}
+
+	unlock, err := d.targetLocks.Lock(req.GetTargetPath())
 	if err != nil {
 		return nil, err
 	}
+	defer unlock()
+
+	err = d.mounter.Unmount(ctx, req.GetTargetPath())
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
 
 	return &csi.NodeUnpublishVolumeResponse{}, nil
 }
 
-func (d *Driver) NodeGetVolumeStats(_ context.Context, _ *csi.NodeGetVolumeStatsRequest) (*csi.NodeGetVolumeStatsResponse, error) {
-	return &csi.NodeGetVolumeStatsResponse{}, nil
+func (d *Driver) NodeGetVolumeStats(_ context.Context, req *csi.NodeGetVolumeStatsRequest) (*csi.NodeGetVolumeStatsResponse, error) {
+	if len(req.GetVolumeId()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "volume id cannot be empty")
+	}
+	if len(req.GetVolumePath()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "volume path cannot be empty")
+	}
+
+	isBlock, err := d.mounter.IsBlockDevice(req.GetVolumePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, status.Errorf(codes.NotFound, "volume path %s not found", req.GetVolumePath())
+		}
+
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	if isBlock {
+		health := d.mounter.CheckBlockDeviceHealth(req.GetVolumePath())
+		if health.Abnormal {
+			return &csi.NodeGetVolumeStatsResponse{
+				VolumeCondition: newNodeVolumeCondition(health),
+			}, nil
+		}
+
+		size, err := d.mounter.GetBlockDeviceSize(req.GetVolumePath())
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+
+		return &csi.NodeGetVolumeStatsResponse{
+			Usage: []*csi.VolumeUsage{
+				{
+					Unit:  csi.VolumeUsage_BYTES,
+					Total: size,
+				},
+			},
+			VolumeCondition: newNodeVolumeCondition(health),
+		}, nil
+	}
+
+	// The staging path keeps the original mount options even if the volume is published read-only.
+	mountPath := req.GetStagingTargetPath()
+	if mountPath == "" {
+		mountPath = req.GetVolumePath()
+	}
+
+	health := d.mounter.CheckFileSystemHealth(mountPath)
+
+	stats, err := d.mounter.GetFileSystemStats(req.GetVolumePath())
+	if err != nil {
+		if health.Abnormal {
+			return &csi.NodeGetVolumeStatsResponse{
+				VolumeCondition: newNodeVolumeCondition(health),
+			}, nil
+		}
+
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &csi.NodeGetVolumeStatsResponse{
+		Usage: []*csi.VolumeUsage{
+			{
+				Unit:      csi.VolumeUsage_BYTES,
+				Total:     stats.TotalBytes,
+				Available: stats.AvailableBytes,
+				Used:      stats.UsedBytes,
+			},
+			{
+				Unit:      csi.VolumeUsage_INODES,
+				Total:     stats.TotalInodes,
+				Available: stats.AvailableInodes,
+				Used:      stats.UsedInodes,
+			},
+		},
+		VolumeCondition: newNodeVolumeCondition(health),
+	}, nil
+}
+
+func newNodeVolumeCondition(health mounter.VolumeHealth) *csi.VolumeCondition {
+	return &csi.VolumeCondition{
+		Abnormal: health.Abnormal,
+		Message:  health.Message,
+	}
 }
 
-func (d *Driver) NodeExpandVolume(_ context.Context, req *csi.NodeExpandVolumeRequest) (*csi.NodeExpandVolumeResponse, error) {
+func (d *Driver) NodeExpandVolume(ctx context.Context, req *csi.NodeExpandVolumeRequest) (*csi.NodeExpandVolumeResponse, error) {
 	volumeID := req.GetVolumeId()
 	volumePath := req.GetVolumePath()
 	if len(volumeID) == 0 {
@@ -74,7 +284,13 @@ func (d *Driver) NodeExpandVolume(_ context.Context, req *csi.NodeExpandVolumeRe
 		return nil, status.Error(codes.InvalidArgument, "volume Path cannot be empty")
 	}
 
-	err := d.mounter.ResizeFS(volumePath)
+	unlock, err := d.stagingLocks.Lock(volumeID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	err = d.mounter.ResizeFS(ctx, volumePath)
 	if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
@@ -86,7 +302,10 @@ func (d *Driver) NodeExpandVolume(_ context.Context, req *csi.NodeExpandVolumeRe
 
 func (d *Driver) NodeGetCapabilities(_ context.Context, _ *csi.NodeGetCapabilitiesRequest) (*csi.NodeGetCapabilitiesResponse, error) {
 	capabilities := []csi.NodeServiceCapability_RPC_Type{
+		csi.NodeServiceCapability_RPC_STAGE_UNSTAGE_VOLUME,
 		csi.NodeServiceCapability_RPC_EXPAND_VOLUME,
+		csi.NodeServiceCapability_RPC_GET_VOLUME_STATS,
+		csi.NodeServiceCapability_RPC_VOLUME_CONDITION,
 	}
 
 	csiCaps := make([]*csi.NodeServiceCapability, len(capabilities))
@@ -105,10 +324,59 @@ func (d *Driver) NodeGetCapabilities(_ context.Context, _ *csi.NodeGetCapabiliti
 	}, nil
 }
 
-func (d *Driver) NodeGetInfo(_ context.Context, _ *csi.NodeGetInfoRequest) (*csi.NodeGetInfoResponse, error) {
+// maxVolumesPerNodeLabel is a label of the guest node overriding the maximum number of disks of its virtual machine.
+const maxVolumesPerNodeLabel = "virtualization.csi.driver.io/max-volumes-per-node"
+
+func (d *Driver) NodeGetInfo(ctx context.Context, _ *csi.NodeGetInfoRequest) (*csi.NodeGetInfoResponse, error) {
+	maxVolumes, err := d.getMaxVolumesPerNode(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	topology, err := d.getNodeTopology(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
 	return &csi.NodeGetInfoResponse{
 		NodeId:             d.nodeName,
-		MaxVolumesPerNode:  10,
-		AccessibleTopology: &csi.Topology{},
+		MaxVolumesPerNode:  maxVolumes,
+		AccessibleTopology: topology,
 	}, nil
 }
+
+// getMaxVolumesPerNode returns the number of volumes that can be attached to the virtual machine: the configured
+// maximum minus the disks attached not by the driver, whether boot, cloud-init or hotplugged ones.
+// The scheduler counts the attached volumes of the driver against the limit itself.
+func (d *Driver) getMaxVolumesPerNode(ctx context.Context) (int64, error) {
+	maxVolumes := d.maxVolumesPerNode
+
+	if d.guestCluster != nil {
+		node, err := d.guestCluster.CoreV1().Nodes().Get(ctx, d.nodeName, metav1.GetOptions{})
+		if err != nil {
+			return 0, fmt.Errorf("failed to get node %s: %w", d.nodeName, err)
+		}
+
+		value, ok := node.Labels[maxVolumesPerNodeLabel]
+		if ok {
+			maxVolumes, err = strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid %s label value %q: %w", maxVolumesPerNodeLabel, value, err)
+			}
+		}
+	}
+
+	attached, err := d.mounter.CountDisks(isVolumeSerial)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count attached disks: %w", err)
+	}
+
+	available := maxVolumes - int64(attached)
+	if available < 1 {
+		// Zero means no limit for the scheduler.
+		d.logger.Warn("No disks can be attached to the node", "max", maxVolumes, "attached", attached)
+		available = 1
+	}
+
+	return available, nil
+}
@@ -0,0 +1,27 @@
+package driver
+
+import "testing"
+
+func TestIsVolumeSerial(t *testing.T) {
+	tests := []struct {
+		serial   string
+		expected bool
+	}{
+		{serial: "pvc-0f8a1c2e-3b4d-4e5f-8a9b-0c1d2e3f4a5b", expected: true},
+		{serial: "pvc-0f8a1c2e-3b4d-4e", expected: true},
+		{serial: "pvc-0f8a1c2e-3b4d-4", expected: false},
+		{serial: "pvc-0f8a1c2e-3b4d-zz", expected: false},
+		{serial: "pvc-0f8a1c2e", expected: false},
+		{serial: "boot-disk", expected: false},
+		{serial: "cloud-init", expected: false},
+		{serial: "", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.serial, func(t *testing.T) {
+			if actual := isVolumeSerial(tt.serial); actual != tt.expected {
+				t.Fatalf("expected %t, got %t", tt.expected, actual)
+			}
+		})
+	}
+}
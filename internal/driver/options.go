@@ -0,0 +1,13 @@
+package driver
+
+type Option interface{}
+
+// MaxVolumesPerNodeOption sets the maximum number of disks a virtual machine can have,
+// including the boot and cloud-init ones.
+type MaxVolumesPerNodeOption struct {
+	Value int64
+}
+
+func NewMaxVolumesPerNodeOption(value int64) *MaxVolumesPerNodeOption {
+	return &MaxVolumesPerNodeOption{Value: value}
+}
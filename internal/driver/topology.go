@@ -0,0 +1,86 @@
+package driver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+)
+
+// topologyZoneKey is a topology key of the host zone the virtual machine is pinned to.
+// The host node itself is not reported: it changes on every live migration, while kubelet refuses
+// to register the driver again with another value of a topology key.
+const topologyZoneKey = "topology.virtualization.csi.driver.io/zone"
+
+// getNodeTopology returns the topology of the node, empty if the host cluster is unavailable to the node.
+func (d *Driver) getNodeTopology(ctx context.Context) (*csi.Topology, error) {
+	if d.hostCluster == nil {
+		return &csi.Topology{}, nil
+	}
+
+	placement, err := d.hostCluster.GetMachinePlacement(ctx, d.nodeName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get virtual machine placement: %w", err)
+	}
+
+	segments := make(map[string]string)
+	if placement.Zone != "" {
+		segments[topologyZoneKey] = placement.Zone
+	}
+
+	return &csi.Topology{
+		Segments: segments,
+	}, nil
+}
+
+// selectZone returns the first zone of the preferred topologies, then of the requisite ones, that has a storage class.
+// It returns an empty zone if the topologies have no zone, and false if none of their zones has a storage class.
+func selectZone(requirements *csi.TopologyRequirement, zoneStorageClasses map[string]string) (string, bool) {
+	var hasZones bool
+
+	for _, topologies := range [][]*csi.Topology{requirements.GetPreferred(), requirements.GetRequisite()} {
+		for _, topology := range topologies {
+			zone := topology.GetSegments()[topologyZoneKey]
+			if zone == "" {
+				continue
+			}
+
+			hasZones = true
+			if _, ok := zoneStorageClasses[zone]; ok {
+				return zone, true
+			}
+		}
+	}
+
+	return "", !hasZones
+}
+
+// parseZoneStorageClasses parses the "zone=storageClass,..." parameter value.
+func parseZoneStorageClasses(value string) (map[string]string, error) {
+	zoneStorageClasses := make(map[string]string)
+	if value == "" {
+		return zoneStorageClasses, nil
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		zone, storageClass, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || zone == "" || storageClass == "" {
+			return nil, fmt.Errorf("invalid %s parameter: expected zone=storageClass pairs, got %q", zoneStorageClassesParameter, pair)
+		}
+
+		zoneStorageClasses[zone] = storageClass
+	}
+
+	return zoneStorageClasses, nil
+}
+
+func isZoneStorageClass(zoneStorageClasses map[string]string, storageClass string) bool {
+	for _, zoneStorageClass := range zoneStorageClasses {
+		if zoneStorageClass == storageClass {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,103 @@
+package driver
+
+import (
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+)
+
+func zoneTopologies(zones ...string) []*csi.Topology {
+	topologies := make([]*csi.Topology, len(zones))
+	for i, zone := range zones {
+		topologies[i] = &csi.Topology{Segments: map[string]string{topologyZoneKey: zone}}
+	}
+
+	return topologies
+}
+
+func TestSelectZone(t *testing.T) {
+	zoneStorageClasses := map[string]string{
+		"zone-b": "local-b",
+		"zone-c": "local-c",
+	}
+
+	tests := []struct {
+		name         string
+		requirements *csi.TopologyRequirement
+		expectedZone string
+		expectedOK   bool
+	}{
+		{
+			name:       "no requirements",
+			expectedOK: true,
+		},
+		{
+			name: "no zones",
+			requirements: &csi.TopologyRequirement{
+				Requisite: []*csi.Topology{{Segments: map[string]string{"other": "value"}}},
+			},
+			expectedOK: true,
+		},
+		{
+			name: "preferred zone",
+			requirements: &csi.TopologyRequirement{
+				Requisite: zoneTopologies("zone-b", "zone-c"),
+				Preferred: zoneTopologies("zone-c", "zone-b"),
+			},
+			expectedZone: "zone-c",
+			expectedOK:   true,
+		},
+		{
+			name: "preferred zone without storage class",
+			requirements: &csi.TopologyRequirement{
+				Requisite: zoneTopologies("zone-a", "zone-b"),
+				Preferred: zoneTopologies("zone-a"),
+			},
+			expectedZone: "zone-b",
+			expectedOK:   true,
+		},
+		{
+			name: "requisite zone",
+			requirements: &csi.TopologyRequirement{
+				Requisite: zoneTopologies("zone-a", "zone-c"),
+			},
+			expectedZone: "zone-c",
+			expectedOK:   true,
+		},
+		{
+			name: "no zone with storage class",
+			requirements: &csi.TopologyRequirement{
+				Requisite: zoneTopologies("zone-a", "zone-d"),
+				Preferred: zoneTopologies("zone-a"),
+			},
+			expectedOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zone, ok := selectZone(tt.requirements, zoneStorageClasses)
+			if zone != tt.expectedZone || ok != tt.expectedOK {
+				t.Fatalf("expected %q, %t, got %q, %t", tt.expectedZone, tt.expectedOK, zone, ok)
+			}
+		})
+	}
+}
+
+func TestParseZoneStorageClasses(t *testing.T) {
+	zoneStorageClasses, err := parseZoneStorageClasses("zone-a=local-a, zone-b=local-b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(zoneStorageClasses) != 2 || zoneStorageClasses["zone-a"] != "local-a" || zoneStorageClasses["zone-b"] != "local-b" {
+		t.Fatalf("unexpected zone storage classes: %v", zoneStorageClasses)
+	}
+
+	for _, value := range []string{"zone-a", "zone-a=", "=local-a", "zone-a=local-a,"} {
+		_, err = parseZoneStorageClasses(value)
+		if err == nil {
+			t.Fatalf("expected an error for %q", value)
+		}
+	}
+}
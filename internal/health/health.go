@@ -0,0 +1,134 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Check is a named check of a prerequisite of the driver.
+type Check struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// Result is the result of the last run of the check.
+type Result struct {
+	Name      string    `json:"name"`
+	Healthy   bool      `json:"healthy"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+
+	err error
+}
+
+// Report is the results of all checks.
+type Report struct {
+	Healthy bool     `json:"healthy"`
+	Checks  []Result `json:"checks"`
+}
+
+// checkTimeout is a time a single check can take.
+const checkTimeout = 10 * time.Second
+
+// Checker runs the checks periodically and keeps their last results.
+type Checker struct {
+	checks   []Check
+	interval time.Duration
+	logger   *slog.Logger
+
+	mu      sync.RWMutex
+	results []Result
+}
+
+func NewChecker(interval time.Duration, logger *slog.Logger, checks ...Check) *Checker {
+	return &Checker{
+		checks:   checks,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Start runs the checks once and then every interval in the background until the context is done.
+func (c *Checker) Start(ctx context.Context) {
+	c.run(ctx)
+
+	go func() {
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.run(ctx)
+			}
+		}
+	}()
+}
+
+func (c *Checker) run(ctx context.Context) {
+	results := make([]Result, len(c.checks))
+
+	for i, check := range c.checks {
+		checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+		err := check.Check(checkCtx)
+		cancel()
+
+		results[i] = Result{
+			Name:      check.Name,
+			Healthy:   err == nil,
+			CheckedAt: time.Now(),
+			err:       err,
+		}
+
+		if err != nil {
+			results[i].Error = err.Error()
+			c.logger.Error("Health check failed", "check", check.Name, "err", err)
+		}
+	}
+
+	c.mu.Lock()
+	c.results = results
+	c.mu.Unlock()
+}
+
+// Report returns the last results of the checks.
+func (c *Checker) Report() Report {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	report := Report{
+		Healthy: true,
+		Checks:  make([]Result, len(c.results)),
+	}
+
+	copy(report.Checks, c.results)
+
+	for _, result := range c.results {
+		if !result.Healthy {
+			report.Healthy = false
+		}
+	}
+
+	return report
+}
+
+// Err returns the errors of the failed checks joined, or nil if all checks passed.
+func (c *Checker) Err() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var errs []error
+	for _, result := range c.results {
+		if result.err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", result.Name, result.err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,56 @@
+package host
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/deckhouse/virtualization/api/core/v1alpha2"
+)
+
+// requiredAccess is the access to the host namespace the controller cannot work without, see deploy/host/rbac.yaml.
+var requiredAccess = []authorizationv1.ResourceAttributes{
+	{Verb: "create", Resource: v1alpha2.VMDResource},
+	{Verb: "delete", Resource: v1alpha2.VMDResource},
+	{Verb: "create", Resource: v1alpha2.VMBDAResource},
+	{Verb: "delete", Resource: v1alpha2.VMBDAResource},
+}
+
+// CheckAccess checks on the host API server that the credentials allow managing the disks and their attachments
+// in the host namespace. It returns ErrAccessDenied listing the missing permissions.
+func (c *Client) CheckAccess(ctx context.Context) error {
+	var denied []string
+
+	for _, access := range requiredAccess {
+		access.Group = v1alpha2.SchemeGroupVersion.Group
+		access.Namespace = c.namespace
+
+		review := &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &access,
+			},
+		}
+
+		err := c.crClient.Create(ctx, review)
+		if err != nil {
+			if k8serrors.IsUnauthorized(err) {
+				return fmt.Errorf("%w: %w", ErrUnauthenticated, err)
+			}
+
+			return fmt.Errorf("failed to review access: %w", err)
+		}
+
+		if !review.Status.Allowed {
+			denied = append(denied, access.Verb+" "+access.Resource)
+		}
+	}
+
+	if len(denied) != 0 {
+		return fmt.Errorf("%w: %s in namespace %s", ErrAccessDenied, strings.Join(denied, ", "), c.namespace)
+	}
+
+	return nil
+}
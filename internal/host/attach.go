@@ -6,9 +6,12 @@ import (
 	"fmt"
 
 	"github.com/google/uuid"
+	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/deckhouse/virtualization/api/core/v1alpha2"
@@ -19,27 +22,60 @@ const (
 	attachmentMachineNameLabel = "virtualMachineName"
 )
 
+// attachmentDiskNameIndex is a cache index of the attachment disk name label.
+const attachmentDiskNameIndex = "metadata.labels." + attachmentDiskNameLabel
+
 type Attachment struct {
 	Name string
+	// Serial is a serial of the disk in the virtual machine.
+	Serial string
+}
+
+// diskSerial returns the serial the host is assumed to hotplug the disk with: its name, which the bus may truncate.
+// The host API reports no serial of the attached disk, neither in the attachment nor in the virtual machine status,
+// so the assumption is not verified. The serial is passed in the publish context so that it can be read
+// from the host once the API reports it, without a change on the node.
+func diskSerial(vmdName string) string {
+	return vmdName
 }
 
-func (c *Client) AttachDisk(ctx context.Context, vmdName, vmName string) (*Attachment, error) {
-	vmbda, err := c.getVMBDA(ctx, vmdName, vmName)
+// AttachDisk attaches the disk to the virtual machine. Unless shared, the disk cannot be attached
+// to several virtual machines at once. Each virtual machine gets its own attachment of the shared disk.
+func (c *Client) AttachDisk(ctx context.Context, vmdName, vmName string, shared bool) (*Attachment, error) {
+	vmbda, err := c.getVMBDA(ctx, c.crClient, vmdName, vmName)
 	if vmbda != nil && err == nil {
-		return &Attachment{Name: vmbda.Name}, nil
+		return &Attachment{Name: vmbda.Name, Serial: diskSerial(vmdName)}, nil
 	}
 
 	if err != nil && !errors.Is(err, ErrAttachmentNotFound) {
 		return nil, err
 	}
 
+	if shared {
+		err = c.checkDiskShareable(ctx, vmdName)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// The attachments are listed on the API server: with a stale cache, two machines could get the disk.
+		vmbdas, err := c.listVMBDAs(ctx, c.apiReader, vmdName, "")
+		if err != nil {
+			return nil, err
+		}
+
+		if len(vmbdas) != 0 {
+			return nil, fmt.Errorf("%w: %s", ErrDiskAttachedToAnotherMachine, vmbdas[0].Spec.VMName)
+		}
+	}
+
 	vmbda = &v1alpha2.VirtualMachineBlockDeviceAttachment{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       v1alpha2.VMBDAKind,
 			APIVersion: v1alpha2.Version,
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "vmbda-" + uuid.New().String(),
+			// The name is derived from the disk and the machine so that a stale read cannot cause a duplicate.
+			Name:      "vmbda-" + uuid.NewSHA1(uuid.NameSpaceOID, []byte(vmdName+"/"+vmName)).String(),
 			Namespace: c.namespace,
 			Labels: map[string]string{
 				attachmentDiskNameLabel:    vmdName,
@@ -62,11 +98,57 @@ func (c *Client) AttachDisk(ctx context.Context, vmdName, vmName string) (*Attac
 		return nil, err
 	}
 
-	return &Attachment{Name: vmbda.Name}, nil
+	return &Attachment{Name: vmbda.Name, Serial: diskSerial(vmdName)}, nil
+}
+
+// checkDiskShareable returns ErrDiskNotShareable unless the host PVC of the disk is ReadWriteMany:
+// the host hotplugs one disk to several virtual machines only if its volume can be shared.
+func (c *Client) checkDiskShareable(ctx context.Context, vmdName string) error {
+	var vmd v1alpha2.VirtualMachineDisk
+
+	err := c.apiReader.Get(ctx, types.NamespacedName{
+		Namespace: c.namespace,
+		Name:      vmdName,
+	}, &vmd)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return ErrDiskNotFound
+		}
+
+		return err
+	}
+
+	pvcName := vmd.Status.Target.PersistentVolumeClaimName
+	if pvcName == "" {
+		return fmt.Errorf("%w: disk has no host PVC yet", ErrDiskNotShareable)
+	}
+
+	var pvc corev1.PersistentVolumeClaim
+
+	err = c.apiReader.Get(ctx, types.NamespacedName{
+		Namespace: c.namespace,
+		Name:      pvcName,
+	}, &pvc)
+	if err != nil {
+		return err
+	}
+
+	for _, accessMode := range pvc.Spec.AccessModes {
+		if accessMode == corev1.ReadWriteMany {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: host PVC %s is not ReadWriteMany", ErrDiskNotShareable, pvcName)
 }
 
 func (c *Client) WaitDiskAttaching(ctx context.Context, attachmentName string) error {
-	return c.Wait(ctx, attachmentName, &v1alpha2.VirtualMachineBlockDeviceAttachment{}, func(obj client.Object) (bool, error) {
+	return c.Wait(ctx, waitOperationAttach, attachmentName, &v1alpha2.VirtualMachineBlockDeviceAttachment{}, func(obj client.Object) (bool, error) {
+		if obj == nil {
+			// Not created yet or not in the cache yet.
+			return false, nil
+		}
+
 		vmd, ok := obj.(*v1alpha2.VirtualMachineBlockDeviceAttachment)
 		if !ok {
 			return false, fmt.Errorf("expected a VirtualMachineBlockDeviceAttachment but got a %T", obj)
@@ -76,28 +158,52 @@ func (c *Client) WaitDiskAttaching(ctx context.Context, attachmentName string) e
 	})
 }
 
-func (c *Client) getVMBDA(ctx context.Context, vmdName, vmName string) (*v1alpha2.VirtualMachineBlockDeviceAttachment, error) {
-	selector, err := labels.Parse(fmt.Sprintf("%s=%s,%s=%s", attachmentDiskNameLabel, vmdName, attachmentMachineNameLabel, vmName))
+func (c *Client) getVMBDA(ctx context.Context, reader client.Reader, vmdName, vmName string) (*v1alpha2.VirtualMachineBlockDeviceAttachment, error) {
+	vmbdas, err := c.listVMBDAs(ctx, reader, vmdName, vmName)
 	if err != nil {
 		return nil, err
 	}
 
-	var vmbdas v1alpha2.VirtualMachineBlockDeviceAttachmentList
-	err = c.crClient.List(ctx, &vmbdas, &client.ListOptions{
-		LabelSelector: selector,
-		Namespace:     c.namespace,
-	})
+	if len(vmbdas) == 0 {
+		return nil, ErrAttachmentNotFound
+	}
+
+	if len(vmbdas) != 1 {
+		return nil, errors.New("more attachments found than expected: please report a bug")
+	}
+
+	return &vmbdas[0], nil
+}
+
+// listVMBDAs lists the attachments of the disk to the virtual machine, or to any virtual machine if vmName is empty,
+// with the reader: the client, which may read from the cache, or the API server reader.
+func (c *Client) listVMBDAs(ctx context.Context, reader client.Reader, vmdName, vmName string) ([]v1alpha2.VirtualMachineBlockDeviceAttachment, error) {
+	set := labels.Set{
+		attachmentDiskNameLabel: vmdName,
+	}
+	if vmName != "" {
+		set[attachmentMachineNameLabel] = vmName
+	}
+
+	selector, err := labels.ValidatedSelectorFromSet(set)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(vmbdas.Items) == 0 {
-		return nil, ErrAttachmentNotFound
+	opts := &client.ListOptions{
+		LabelSelector: selector,
+		Namespace:     c.namespace,
+	}
+	if c.cachedReads && reader != c.apiReader {
+		// The cache filters by labels linearly, so narrow the list down by the index first.
+		opts.FieldSelector = fields.OneTermEqualSelector(attachmentDiskNameIndex, vmdName)
 	}
 
-	if len(vmbdas.Items) != 1 {
-		return nil, errors.New("more attachments found than expected: please report a bug")
+	var vmbdas v1alpha2.VirtualMachineBlockDeviceAttachmentList
+	err = reader.List(ctx, &vmbdas, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	return &vmbdas.Items[0], nil
+	return vmbdas.Items, nil
 }
@@ -0,0 +1,119 @@
+package host
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/deckhouse/virtualization/api/core/v1alpha2"
+)
+
+func newTestAttachment(vmdName, vmName string) *v1alpha2.VirtualMachineBlockDeviceAttachment {
+	return &v1alpha2.VirtualMachineBlockDeviceAttachment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "vmbda-" + vmdName + "-" + vmName,
+			Namespace: "test",
+			Labels: map[string]string{
+				attachmentDiskNameLabel:    vmdName,
+				attachmentMachineNameLabel: vmName,
+			},
+		},
+		Spec: v1alpha2.VirtualMachineBlockDeviceAttachmentSpec{
+			VMName: vmName,
+		},
+	}
+}
+
+func newTestClaimWithAccessMode(name string, accessMode corev1.PersistentVolumeAccessMode) *corev1.PersistentVolumeClaim {
+	pvc := newTestClaim(name, "shared")
+	pvc.Spec.AccessModes = []corev1.PersistentVolumeAccessMode{accessMode}
+
+	return pvc
+}
+
+// withCachedReads makes the client read from the cache and write to the API server, as with the cached reads.
+func withCachedReads(c *Client, cacheClient client.Client) {
+	c.crClient = interceptor.NewClient(c.crClient.(client.WithWatch), interceptor.Funcs{
+		Get: func(ctx context.Context, _ client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+			return cacheClient.Get(ctx, key, obj, opts...)
+		},
+		List: func(ctx context.Context, _ client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+			return cacheClient.List(ctx, list, opts...)
+		},
+	})
+}
+
+func TestAttachDiskExclusive(t *testing.T) {
+	// The attachment to another machine is not in the cache yet.
+	c, cacheClient := newTestClient(t, []client.Object{newTestAttachment("disk", "vm-1")}, nil)
+	withCachedReads(c, cacheClient)
+
+	_, err := c.AttachDisk(context.Background(), "disk", "vm-2", false)
+	if !errors.Is(err, ErrDiskAttachedToAnotherMachine) {
+		t.Fatalf("expected the disk attached to another machine, got %v", err)
+	}
+}
+
+func TestAttachDiskShared(t *testing.T) {
+	tests := []struct {
+		name string
+		objs []client.Object
+		err  error
+	}{
+		{
+			name: "ReadWriteMany claim",
+			objs: []client.Object{
+				newTestDiskOnClaim("disk", "disk-pvc"),
+				newTestClaimWithAccessMode("disk-pvc", corev1.ReadWriteMany),
+				newTestAttachment("disk", "vm-1"),
+			},
+		},
+		{
+			name: "ReadWriteOnce claim",
+			objs: []client.Object{
+				newTestDiskOnClaim("disk", "disk-pvc"),
+				newTestClaimWithAccessMode("disk-pvc", corev1.ReadWriteOnce),
+			},
+			err: ErrDiskNotShareable,
+		},
+		{
+			name: "no claim yet",
+			objs: []client.Object{newTestDiskInPhase("disk", v1alpha2.DiskProvisioning)},
+			err:  ErrDiskNotShareable,
+		},
+		{
+			name: "no disk",
+			err:  ErrDiskNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newTestClient(t, tt.objs, nil)
+
+			attachment, err := c.AttachDisk(context.Background(), "disk", "vm-2", true)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected %v, got %v", tt.err, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var vmbda v1alpha2.VirtualMachineBlockDeviceAttachment
+			err = c.apiReader.Get(context.Background(), client.ObjectKey{Namespace: "test", Name: attachment.Name}, &vmbda)
+			if err != nil {
+				t.Fatalf("expected the attachment to be created: %v", err)
+			}
+		})
+	}
+}
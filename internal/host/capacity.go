@@ -0,0 +1,87 @@
+package host
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// storageClassRequestsStorageSuffix is a suffix of the quota resource limiting the storage of a storage class.
+const storageClassRequestsStorageSuffix = ".storageclass.storage.k8s.io/requests.storage"
+
+type Capacity struct {
+	// Available is a storage left by the quotas of the host namespace, nil if no quota limits it.
+	Available *resource.Quantity
+	// MaximumVolumeSize is a size limit of a single disk in the host namespace, nil if no limit range sets it.
+	MaximumVolumeSize *resource.Quantity
+}
+
+// GetCapacity returns a storage available for the disks in the host namespace.
+// If the storage class is not empty, its own quota is taken into account too.
+func (c *Client) GetCapacity(ctx context.Context, storageClass string) (*Capacity, error) {
+	var quotas corev1.ResourceQuotaList
+	err := c.crClient.List(ctx, &quotas, &client.ListOptions{
+		Namespace: c.namespace,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resourceNames := []corev1.ResourceName{corev1.ResourceRequestsStorage}
+	if storageClass != "" {
+		resourceNames = append(resourceNames, corev1.ResourceName(storageClass+storageClassRequestsStorageSuffix))
+	}
+
+	var capacity Capacity
+
+	for _, quota := range quotas.Items {
+		for _, resourceName := range resourceNames {
+			hard, ok := quota.Status.Hard[resourceName]
+			if !ok {
+				hard, ok = quota.Spec.Hard[resourceName]
+				if !ok {
+					continue
+				}
+			}
+
+			available := hard.DeepCopy()
+			available.Sub(quota.Status.Used[resourceName])
+			if available.Sign() < 0 {
+				available = *resource.NewQuantity(0, resource.BinarySI)
+			}
+
+			if capacity.Available == nil || available.Cmp(*capacity.Available) < 0 {
+				capacity.Available = &available
+			}
+		}
+	}
+
+	var limitRanges corev1.LimitRangeList
+	err = c.crClient.List(ctx, &limitRanges, &client.ListOptions{
+		Namespace: c.namespace,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, limitRange := range limitRanges.Items {
+		for _, limit := range limitRange.Spec.Limits {
+			if limit.Type != corev1.LimitTypePersistentVolumeClaim {
+				continue
+			}
+
+			maximum, ok := limit.Max[corev1.ResourceStorage]
+			if !ok {
+				continue
+			}
+
+			if capacity.MaximumVolumeSize == nil || maximum.Cmp(*capacity.MaximumVolumeSize) < 0 {
+				capacity.MaximumVolumeSize = &maximum
+			}
+		}
+	}
+
+	return &capacity, nil
+}
@@ -0,0 +1,156 @@
+package host
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newTestCapacityClient(t *testing.T, objs ...client.Object) *Client {
+	t.Helper()
+
+	scheme := runtime.NewScheme()
+	err := corev1.AddToScheme(scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	apiClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+
+	return &Client{
+		crClient:  apiClient,
+		apiReader: apiClient,
+		namespace: "test",
+	}
+}
+
+func newTestQuota(name string, hard, used corev1.ResourceList) *corev1.ResourceQuota {
+	return &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test"},
+		Spec:       corev1.ResourceQuotaSpec{Hard: hard},
+		Status:     corev1.ResourceQuotaStatus{Hard: hard, Used: used},
+	}
+}
+
+func newTestLimitRange(name string, limits ...corev1.LimitRangeItem) *corev1.LimitRange {
+	return &corev1.LimitRange{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test"},
+		Spec:       corev1.LimitRangeSpec{Limits: limits},
+	}
+}
+
+func expectQuantity(t *testing.T, name string, expected string, actual *resource.Quantity) {
+	t.Helper()
+
+	if expected == "" {
+		if actual != nil {
+			t.Fatalf("expected no %s, got %s", name, actual.String())
+		}
+
+		return
+	}
+
+	if actual == nil || actual.Cmp(resource.MustParse(expected)) != 0 {
+		t.Fatalf("expected %s %s, got %v", name, expected, actual)
+	}
+}
+
+func TestGetCapacity(t *testing.T) {
+	const storageClassResource = corev1.ResourceName("fast" + storageClassRequestsStorageSuffix)
+
+	objs := []client.Object{
+		newTestQuota("total",
+			corev1.ResourceList{corev1.ResourceRequestsStorage: resource.MustParse("100Gi")},
+			corev1.ResourceList{corev1.ResourceRequestsStorage: resource.MustParse("40Gi")},
+		),
+		newTestQuota("fast",
+			corev1.ResourceList{storageClassResource: resource.MustParse("50Gi")},
+			corev1.ResourceList{storageClassResource: resource.MustParse("30Gi")},
+		),
+		newTestLimitRange("limits",
+			corev1.LimitRangeItem{
+				Type: corev1.LimitTypeContainer,
+				Max:  corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")},
+			},
+			corev1.LimitRangeItem{
+				Type: corev1.LimitTypePersistentVolumeClaim,
+				Max:  corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("20Gi")},
+			},
+		),
+		newTestLimitRange("stricter",
+			corev1.LimitRangeItem{
+				Type: corev1.LimitTypePersistentVolumeClaim,
+				Max:  corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("10Gi")},
+			},
+		),
+	}
+
+	tests := []struct {
+		name              string
+		objs              []client.Object
+		storageClass      string
+		available         string
+		maximumVolumeSize string
+	}{
+		{
+			name: "no limits",
+		},
+		{
+			name:              "namespace quota and limit ranges",
+			objs:              objs,
+			available:         "60Gi",
+			maximumVolumeSize: "10Gi",
+		},
+		{
+			name:              "storage class quota",
+			objs:              objs,
+			storageClass:      "fast",
+			available:         "20Gi",
+			maximumVolumeSize: "10Gi",
+		},
+		{
+			name:              "quota of another storage class",
+			objs:              objs,
+			storageClass:      "slow",
+			available:         "60Gi",
+			maximumVolumeSize: "10Gi",
+		},
+		{
+			name: "quota exceeded",
+			objs: []client.Object{
+				newTestQuota("total",
+					corev1.ResourceList{corev1.ResourceRequestsStorage: resource.MustParse("10Gi")},
+					corev1.ResourceList{corev1.ResourceRequestsStorage: resource.MustParse("12Gi")},
+				),
+			},
+			available: "0",
+		},
+		{
+			name: "quota of other resources",
+			objs: []client.Object{
+				newTestQuota("pods",
+					corev1.ResourceList{corev1.ResourcePods: resource.MustParse("10")},
+					corev1.ResourceList{corev1.ResourcePods: resource.MustParse("1")},
+				),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			capacity, err := newTestCapacityClient(t, tt.objs...).GetCapacity(context.Background(), tt.storageClass)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expectQuantity(t, "available capacity", tt.available, capacity.Available)
+			expectQuantity(t, "maximum volume size", tt.maximumVolumeSize, capacity.MaximumVolumeSize)
+		})
+	}
+}
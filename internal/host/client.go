@@ -1,26 +1,59 @@
 package host
 
 import (
+	"context"
 	"encoding/base64"
 	"errors"
+	"fmt"
+	"log/slog"
 	"os"
+	"time"
 
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/deckhouse/virtualization/api/core/v1alpha2"
 )
 
+// defaultCacheSyncTimeout is a time to wait for the informers to list the host objects on start.
+const defaultCacheSyncTimeout = 2 * time.Minute
+
 type Client struct {
-	crClient  client.Client
-	namespace string
+	// crClient reads from the cache if cachedReads is set, and writes to the API server.
+	crClient client.Client
+	// apiReader reads from the API server directly.
+	apiReader client.Reader
+	// cache is a namespace-scoped cache of the shared informers for the host objects, nil without informers.
+	cache       cache.Cache
+	cachedReads bool
+	watcher     *watcher
+	credentials *credentials
+	namespace   string
+	// resyncInterval is an interval to check the awaited object on the API server.
+	resyncInterval time.Duration
 }
 
-func NewClient() (*Client, error) {
-	kubeconfig := os.Getenv("HOST_KUBECONFIG")
-	if kubeconfig == "" {
-		return nil, errors.New("kubeconfig env not found")
+// NewClient returns a client to the host cluster. The shared informers are run until the context is done.
+func NewClient(ctx context.Context, options ...Option) (*Client, error) {
+	var cachedReads, withoutInformers bool
+	var kubeconfigFile string
+	logger := slog.Default()
+
+	for _, option := range options {
+		switch o := option.(type) {
+		case *CachedReadsOption:
+			cachedReads = true
+		case *WithoutInformersOption:
+			withoutInformers = true
+		case *KubeconfigFileOption:
+			kubeconfigFile = o.Path
+		case *LoggerOption:
+			logger = o.Logger
+		default:
+		}
 	}
 
 	hostNamespace := os.Getenv("HOST_NAMESPACE")
@@ -28,36 +61,179 @@ func NewClient() (*Client, error) {
 		return nil, errors.New("host namespace env not found")
 	}
 
-	kubeconfigBase64, err := base64.StdEncoding.DecodeString(kubeconfig)
+	kubeconfig, err := readKubeconfig(kubeconfigFile)
 	if err != nil {
 		return nil, err
 	}
 
-	clientConfig, err := clientcmd.NewClientConfigFromBytes(kubeconfigBase64)
+	creds, config, err := newCredentials(kubeconfig, logger)
 	if err != nil {
 		return nil, err
 	}
 
-	config, err := clientConfig.ClientConfig()
-	if err != nil {
-		return nil, err
+	if kubeconfigFile != "" {
+		err = creds.watch(ctx, kubeconfigFile)
+		if err != nil {
+			return nil, err
+		}
 	}
 
+	config.Wrap(newMetricsRoundTripper)
+	config.Wrap(newTracingRoundTripper)
+
 	scheme := runtime.NewScheme()
 	err = v1alpha2.AddToScheme(scheme)
 	if err != nil {
 		return nil, err
 	}
 
-	crClient, err := client.New(config, client.Options{
+	err = corev1.AddToScheme(scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	err = authorizationv1.AddToScheme(scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	apiReader, err := client.New(config, client.Options{
+		Scheme: scheme,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if withoutInformers {
+		return &Client{
+			crClient:    apiReader,
+			apiReader:   apiReader,
+			credentials: creds,
+			namespace:   hostNamespace,
+		}, nil
+	}
+
+	informerCache, err := cache.New(config, cache.Options{
 		Scheme: scheme,
+		DefaultNamespaces: map[string]cache.Config{
+			hostNamespace: {},
+		},
 	})
 	if err != nil {
 		return nil, err
 	}
 
+	crClient := apiReader
+	if cachedReads {
+		// Writes go straight to the API server anyway.
+		crClient, err = client.New(config, client.Options{
+			Scheme: scheme,
+			Cache: &client.CacheOptions{
+				Reader: informerCache,
+				// Not watched: read rarely.
+				DisableFor: []client.Object{
+					&corev1.ResourceQuota{},
+					&corev1.LimitRange{},
+				},
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	w := newWatcher()
+
+	for _, obj := range []client.Object{
+		&v1alpha2.VirtualMachineDisk{},
+		&v1alpha2.VirtualMachineBlockDeviceAttachment{},
+		&v1alpha2.VirtualMachine{},
+		// Watched for the waits of the storage class changes only.
+		&corev1.PersistentVolumeClaim{},
+	} {
+		informer, err := informerCache.GetInformer(ctx, obj)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = informer.AddEventHandler(w.EventHandler())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = informerCache.IndexField(ctx, &v1alpha2.VirtualMachineBlockDeviceAttachment{}, attachmentDiskNameIndex, labelIndexer(attachmentDiskNameLabel))
+	if err != nil {
+		return nil, err
+	}
+
+	started := make(chan error, 1)
+	go func() {
+		err := informerCache.Start(ctx)
+		if err != nil {
+			logger.Error("Host cluster cache stopped", "err", err)
+		}
+		started <- err
+	}()
+
+	syncCtx, cancel := context.WithTimeout(ctx, defaultCacheSyncTimeout)
+	defer cancel()
+
+	if !informerCache.WaitForCacheSync(syncCtx) {
+		select {
+		case err := <-started:
+			if err != nil {
+				return nil, fmt.Errorf("failed to start host cluster cache: %w", err)
+			}
+		default:
+		}
+
+		return nil, fmt.Errorf("failed to sync host cluster cache in %s: check the list and watch permissions in the host namespace", defaultCacheSyncTimeout)
+	}
+
 	return &Client{
-		crClient:  crClient,
-		namespace: hostNamespace,
+		crClient:       crClient,
+		apiReader:      apiReader,
+		cache:          informerCache,
+		cachedReads:    cachedReads,
+		watcher:        w,
+		credentials:    creds,
+		namespace:      hostNamespace,
+		resyncInterval: defaultWaitResyncInterval,
 	}, nil
 }
+
+// readKubeconfig reads the kubeconfig from the file, if any, or decodes it from the HOST_KUBECONFIG env.
+func readKubeconfig(path string) ([]byte, error) {
+	if path != "" {
+		kubeconfig, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read kubeconfig file: %w", err)
+		}
+
+		return kubeconfig, nil
+	}
+
+	kubeconfig := os.Getenv("HOST_KUBECONFIG")
+	if kubeconfig == "" {
+		return nil, errors.New("kubeconfig env not found")
+	}
+
+	return base64.StdEncoding.DecodeString(kubeconfig)
+}
+
+// CheckCredentials returns ErrUnauthenticated if the host API server rejected the credentials of the last request.
+func (c *Client) CheckCredentials() error {
+	return c.credentials.Check()
+}
+
+func labelIndexer(label string) client.IndexerFunc {
+	return func(obj client.Object) []string {
+		value, ok := obj.GetLabels()[label]
+		if !ok {
+			return nil
+		}
+
+		return []string{value}
+	}
+}
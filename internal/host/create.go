@@ -12,12 +12,58 @@ import (
 	"github.com/deckhouse/virtualization/api/core/v1alpha2"
 )
 
+const (
+	diskManagedByLabel = "app.kubernetes.io/managed-by"
+	diskManagedByValue = "dvp-csi-driver"
+)
+
 type Disk struct {
 	Name     string
 	Capacity resource.Quantity
+	// StorageClass is a name of the host storage class, empty for the default one.
+	StorageClass string
+	Source       *DiskSource
+	Condition    DiskCondition
+}
+
+// DiskCondition is a health of the disk derived from its phase on the host.
+type DiskCondition struct {
+	Abnormal bool
+	Message  string
+}
+
+// DiskSource is a host image to populate the disk from. At most one field is expected to be set.
+type DiskSource struct {
+	// VirtualMachineImage is a name of the VirtualMachineImage in the host namespace.
+	VirtualMachineImage string
+	// ClusterVirtualMachineImage is a name of the ClusterVirtualMachineImage.
+	ClusterVirtualMachineImage string
+}
+
+func (s *DiskSource) dataSource() *v1alpha2.VMDDataSource {
+	switch {
+	case s == nil:
+		return nil
+	case s.VirtualMachineImage != "":
+		return &v1alpha2.VMDDataSource{
+			Type: v1alpha2.DataSourceTypeVirtualMachineImage,
+			VirtualMachineImage: &v1alpha2.DataSourceNamedRef{
+				Name: s.VirtualMachineImage,
+			},
+		}
+	case s.ClusterVirtualMachineImage != "":
+		return &v1alpha2.VMDDataSource{
+			Type: v1alpha2.DataSourceTypeClusterVirtualMachineImage,
+			ClusterVirtualMachineImage: &v1alpha2.DataSourceNamedRef{
+				Name: s.ClusterVirtualMachineImage,
+			},
+		}
+	default:
+		return nil
+	}
 }
 
-func (c *Client) CreateDisk(ctx context.Context, name string, size int64, storageClass *string) (*Disk, error) {
+func (c *Client) CreateDisk(ctx context.Context, name string, size int64, storageClass *string, source *DiskSource) (*Disk, error) {
 	vmd := v1alpha2.VirtualMachineDisk{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       v1alpha2.VMDKind,
@@ -26,8 +72,12 @@ func (c *Client) CreateDisk(ctx context.Context, name string, size int64, storag
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      name,
 			Namespace: c.namespace,
+			Labels: map[string]string{
+				diskManagedByLabel: diskManagedByValue,
+			},
 		},
 		Spec: v1alpha2.VirtualMachineDiskSpec{
+			DataSource: source.dataSource(),
 			PersistentVolumeClaim: v1alpha2.VMDPersistentVolumeClaim{
 				StorageClassName: storageClass,
 				Size:             resource.NewQuantity(size, resource.BinarySI),
@@ -46,7 +96,12 @@ func (c *Client) CreateDisk(ctx context.Context, name string, size int64, storag
 }
 
 func (c *Client) WaitDiskCreation(ctx context.Context, vmdName string) error {
-	return c.Wait(ctx, vmdName, &v1alpha2.VirtualMachineDisk{}, func(obj client.Object) (bool, error) {
+	return c.Wait(ctx, waitOperationCreate, vmdName, &v1alpha2.VirtualMachineDisk{}, func(obj client.Object) (bool, error) {
+		if obj == nil {
+			// Not created yet or not in the cache yet.
+			return false, nil
+		}
+
 		vmd, ok := obj.(*v1alpha2.VirtualMachineDisk)
 		if !ok {
 			return false, fmt.Errorf("expected a VirtualMachineDisk but got a %T", obj)
@@ -0,0 +1,182 @@
+package host
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/fsnotify/fsnotify"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// credentials sends the requests to the host API server with the current credentials of the kubeconfig.
+// The kubeconfig can be replaced at any time: the requests in flight complete with the previous credentials,
+// and the clients and informers built on top of it keep working.
+type credentials struct {
+	// host is the API server of the kubeconfig, which must not change.
+	host      string
+	current   atomic.Pointer[credentialsTransport]
+	authError atomic.Pointer[error]
+	logger    *slog.Logger
+}
+
+type credentialsTransport struct {
+	kubeconfig []byte
+	transport  http.RoundTripper
+}
+
+func newCredentials(kubeconfig []byte, logger *slog.Logger) (*credentials, *rest.Config, error) {
+	config, transport, err := parseKubeconfig(kubeconfig)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c := &credentials{
+		host:   config.Host,
+		logger: logger,
+	}
+	c.current.Store(&credentialsTransport{
+		kubeconfig: kubeconfig,
+		transport:  transport,
+	})
+
+	// The TLS and authentication settings are in the transport, only the server is left.
+	clientConfig := rest.AnonymousClientConfig(config)
+	clientConfig.TLSClientConfig = rest.TLSClientConfig{}
+	clientConfig.Transport = c
+
+	return c, clientConfig, nil
+}
+
+func parseKubeconfig(kubeconfig []byte) (*rest.Config, http.RoundTripper, error) {
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse kubeconfig: %w", err)
+	}
+
+	transport, err := rest.TransportFor(config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create transport: %w", err)
+	}
+
+	return config, transport, nil
+}
+
+func (c *credentials) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := c.current.Load().transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch res.StatusCode {
+	case http.StatusUnauthorized:
+		authErr := fmt.Errorf("%w: %s %s", ErrUnauthenticated, req.Method, req.URL.Path)
+		c.authError.Store(&authErr)
+	default:
+		c.authError.Store(nil)
+	}
+
+	return res, nil
+}
+
+// Check returns ErrUnauthenticated if the host API server rejected the credentials of the last request.
+func (c *credentials) Check() error {
+	authErr := c.authError.Load()
+	if authErr == nil {
+		return nil
+	}
+
+	return *authErr
+}
+
+// reload replaces the credentials with the ones of the kubeconfig, if changed.
+func (c *credentials) reload(kubeconfig []byte) error {
+	previous := c.current.Load()
+	if bytes.Equal(previous.kubeconfig, kubeconfig) {
+		return nil
+	}
+
+	c.logger.Info("Reloading host kubeconfig")
+
+	config, transport, err := parseKubeconfig(kubeconfig)
+	if err != nil {
+		return err
+	}
+
+	if config.Host != c.host {
+		return fmt.Errorf("kubeconfig server changed from %s to %s: restart required", c.host, config.Host)
+	}
+
+	c.current.Store(&credentialsTransport{
+		kubeconfig: kubeconfig,
+		transport:  transport,
+	})
+	c.authError.Store(nil)
+
+	// The connections in use are closed once their requests complete.
+	utilnet.CloseIdleConnectionsFor(previous.transport)
+
+	return nil
+}
+
+// watch reloads the credentials every time the kubeconfig file changes until the context is done.
+// The directory is watched, as the kubelet updates the mounted Secret by replacing a symlink.
+func (c *credentials) watch(ctx context.Context, path string) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create kubeconfig watcher: %w", err)
+	}
+
+	err = watcher.Add(filepath.Dir(path))
+	if err != nil {
+		_ = watcher.Close()
+		return fmt.Errorf("failed to watch kubeconfig directory: %w", err)
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+
+				kubeconfig, err := os.ReadFile(path)
+				if err != nil {
+					// The file is being replaced: the next event brings it.
+					if !errors.Is(err, os.ErrNotExist) {
+						c.logger.Error("Failed to read host kubeconfig", "path", path, "err", err)
+					}
+
+					continue
+				}
+
+				err = c.reload(kubeconfig)
+				if err != nil {
+					c.logger.Error("Failed to reload host kubeconfig", "path", path, "err", err)
+					continue
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+
+				c.logger.Error("Host kubeconfig watcher failed", "err", err)
+			}
+		}
+	}()
+
+	return nil
+}
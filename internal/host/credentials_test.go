@@ -0,0 +1,251 @@
+package host
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestKubeconfig(server, token string) []byte {
+	return []byte(fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: host
+  cluster:
+    server: %s
+    insecure-skip-tls-verify: true
+users:
+- name: csi
+  user:
+    token: %s
+contexts:
+- name: host
+  context:
+    cluster: host
+    user: csi
+current-context: host
+`, server, token))
+}
+
+// newTokenServer returns the server accepting the requests with the token only.
+// The server serves TLS, as the bearer tokens are not sent over plain HTTP.
+func newTokenServer(t *testing.T, token string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func newTestCredentials(t *testing.T, kubeconfig []byte) *credentials {
+	t.Helper()
+
+	creds, _, err := newCredentials(kubeconfig, slog.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return creds
+}
+
+// sendRequest sends the request to the server with the credentials and returns the status code of the response.
+func sendRequest(t *testing.T, creds *credentials, server string) int {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, server+"/api", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := creds.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+
+	return res.StatusCode
+}
+
+func TestCredentialsAuthError(t *testing.T) {
+	server := newTokenServer(t, "valid")
+	creds := newTestCredentials(t, newTestKubeconfig(server.URL, "valid"))
+
+	if code := sendRequest(t, creds, server.URL); code != http.StatusOK || creds.Check() != nil {
+		t.Fatalf("expected the credentials to be accepted, got %d, %v", code, creds.Check())
+	}
+
+	err := creds.reload(newTestKubeconfig(server.URL, "revoked"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if code := sendRequest(t, creds, server.URL); code != http.StatusUnauthorized || !errors.Is(creds.Check(), ErrUnauthenticated) {
+		t.Fatalf("expected the credentials to be rejected, got %d, %v", code, creds.Check())
+	}
+
+	// The new credentials are not rejected until they are used.
+	err = creds.reload(newTestKubeconfig(server.URL, "valid"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if creds.Check() != nil {
+		t.Fatalf("expected the auth error to be cleared on reload, got %v", creds.Check())
+	}
+
+	creds.authError.Store(&ErrUnauthenticated)
+
+	if code := sendRequest(t, creds, server.URL); code != http.StatusOK || creds.Check() != nil {
+		t.Fatalf("expected the auth error to be cleared by the accepted request, got %d, %v", code, creds.Check())
+	}
+}
+
+func TestCredentialsReloadSwapsTransport(t *testing.T) {
+	received := make(chan string, 2)
+	release := make(chan struct{})
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get("Authorization")
+		received <- token
+
+		// The request with the previous credentials is in flight until released.
+		if token == "Bearer previous" {
+			<-release
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	creds := newTestCredentials(t, newTestKubeconfig(server.URL, "previous"))
+
+	inFlight := make(chan int, 1)
+	go func() {
+		inFlight <- sendRequest(t, creds, server.URL)
+	}()
+
+	if token := <-received; token != "Bearer previous" {
+		t.Fatalf("expected the previous token, got %q", token)
+	}
+
+	err := creds.reload(newTestKubeconfig(server.URL, "current"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if code := sendRequest(t, creds, server.URL); code != http.StatusOK {
+		t.Fatalf("expected the request to succeed, got %d", code)
+	}
+
+	if token := <-received; token != "Bearer current" {
+		t.Fatalf("expected the current token, got %q", token)
+	}
+
+	close(release)
+
+	select {
+	case code := <-inFlight:
+		if code != http.StatusOK {
+			t.Fatalf("expected the request in flight to complete with the previous credentials, got %d", code)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("request in flight did not complete")
+	}
+}
+
+func TestCredentialsReloadServerChanged(t *testing.T) {
+	server := newTokenServer(t, "valid")
+	creds := newTestCredentials(t, newTestKubeconfig(server.URL, "valid"))
+
+	err := creds.reload(newTestKubeconfig("https://other.example.com", "valid"))
+	if err == nil {
+		t.Fatal("expected an error for the changed server")
+	}
+
+	if code := sendRequest(t, creds, server.URL); code != http.StatusOK {
+		t.Fatalf("expected the previous credentials to be kept, got %d", code)
+	}
+}
+
+func TestCredentialsWatch(t *testing.T) {
+	server := newTokenServer(t, "current")
+
+	// The kubelet mounts the Secret key as a symlink to the ..data symlink to the directory of the current version,
+	// and updates it by replacing the ..data symlink.
+	dir := t.TempDir()
+	writeVersion := func(version string, kubeconfig []byte) {
+		t.Helper()
+
+		err := os.Mkdir(filepath.Join(dir, version), 0o700)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = os.WriteFile(filepath.Join(dir, version, "kubeconfig"), kubeconfig, 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = os.Symlink(version, filepath.Join(dir, "..data_tmp"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data"))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	previous := newTestKubeconfig(server.URL, "previous")
+	writeVersion("..v1", previous)
+
+	path := filepath.Join(dir, "kubeconfig")
+	err := os.Symlink(filepath.Join("..data", "kubeconfig"), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	creds := newTestCredentials(t, previous)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	err = creds.watch(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if code := sendRequest(t, creds, server.URL); code != http.StatusUnauthorized {
+		t.Fatalf("expected the previous credentials to be rejected, got %d", code)
+	}
+
+	writeVersion("..v2", newTestKubeconfig(server.URL, "current"))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for sendRequest(t, creds, server.URL) != http.StatusOK {
+		if time.Now().After(deadline) {
+			t.Fatal("credentials were not reloaded")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if creds.Check() != nil {
+		t.Fatalf("expected no auth error after the reload, got %v", creds.Check())
+	}
+}
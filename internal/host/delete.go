@@ -4,19 +4,20 @@ import (
 	"context"
 
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/deckhouse/virtualization/api/core/v1alpha2"
 )
 
+// DeleteDisk deletes the disk by name, without reading it first: the cache may not have a disk created moments ago.
 func (c *Client) DeleteDisk(ctx context.Context, vmdName string) (*Disk, error) {
-	var vmd v1alpha2.VirtualMachineDisk
-
-	err := c.crClient.Get(ctx, types.NamespacedName{
-		Namespace: c.namespace,
-		Name:      vmdName,
-	}, &vmd)
+	err := c.crClient.Delete(ctx, &v1alpha2.VirtualMachineDisk{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: c.namespace,
+			Name:      vmdName,
+		},
+	})
 	if err != nil {
 		if k8serrors.IsNotFound(err) {
 			return nil, ErrDiskAlreadyDeleted
@@ -25,16 +26,11 @@ func (c *Client) DeleteDisk(ctx context.Context, vmdName string) (*Disk, error)
 		return nil, err
 	}
 
-	err = c.crClient.Delete(ctx, &vmd)
-	if err != nil {
-		return nil, err
-	}
-
 	return &Disk{Name: vmdName}, nil
 }
 
 func (c *Client) WaitDiskDeletion(ctx context.Context, vmdName string) error {
-	return c.Wait(ctx, vmdName, &v1alpha2.VirtualMachineDisk{}, func(obj client.Object) (bool, error) {
+	return c.Wait(ctx, waitOperationDelete, vmdName, &v1alpha2.VirtualMachineDisk{}, func(obj client.Object) (bool, error) {
 		return obj == nil, nil
 	})
 }
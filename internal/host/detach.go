@@ -4,13 +4,16 @@ import (
 	"context"
 	"errors"
 
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/deckhouse/virtualization/api/core/v1alpha2"
 )
 
+// DetachDisk deletes the attachment of the disk to the virtual machine. The attachment is looked up
+// on the API server: the cache may not have an attachment created moments ago.
 func (c *Client) DetachDisk(ctx context.Context, vmdName, vmName string) (*Attachment, error) {
-	vmbda, err := c.getVMBDA(ctx, vmdName, vmName)
+	vmbda, err := c.getVMBDA(ctx, c.apiReader, vmdName, vmName)
 	if err != nil {
 		if errors.Is(err, ErrAttachmentNotFound) {
 			return nil, ErrAttachmentAlreadyDeleted
@@ -21,6 +24,10 @@ func (c *Client) DetachDisk(ctx context.Context, vmdName, vmName string) (*Attac
 
 	err = c.crClient.Delete(ctx, vmbda)
 	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, ErrAttachmentAlreadyDeleted
+		}
+
 		return nil, err
 	}
 
@@ -28,7 +35,7 @@ func (c *Client) DetachDisk(ctx context.Context, vmdName, vmName string) (*Attac
 }
 
 func (c *Client) WaitDiskDetaching(ctx context.Context, attachmentName string) error {
-	return c.Wait(ctx, attachmentName, &v1alpha2.VirtualMachineBlockDeviceAttachment{}, func(obj client.Object) (bool, error) {
+	return c.Wait(ctx, waitOperationDetach, attachmentName, &v1alpha2.VirtualMachineBlockDeviceAttachment{}, func(obj client.Object) (bool, error) {
 		return obj == nil, nil
 	})
 }
@@ -3,8 +3,14 @@ package host
 import "errors"
 
 var (
-	ErrDiskAlreadyDeleted       = errors.New("disk already exists")
-	ErrAttachmentAlreadyDeleted = errors.New("attachment already exists")
-	ErrAttachmentNotFound       = errors.New("attachment not found")
-	ErrDiskNotFound             = errors.New("disk not found")
+	ErrDiskAlreadyDeleted           = errors.New("disk already exists")
+	ErrAttachmentAlreadyDeleted     = errors.New("attachment already exists")
+	ErrAttachmentNotFound           = errors.New("attachment not found")
+	ErrDiskNotFound                 = errors.New("disk not found")
+	ErrInvalidContinueToken         = errors.New("invalid or expired continue token")
+	ErrMachineNotFound              = errors.New("virtual machine not found")
+	ErrDiskAttachedToAnotherMachine = errors.New("disk is attached to another virtual machine")
+	ErrDiskNotShareable             = errors.New("disk cannot be attached to several virtual machines")
+	ErrUnauthenticated              = errors.New("host cluster rejected the credentials")
+	ErrAccessDenied                 = errors.New("host cluster denied access")
 )
@@ -2,6 +2,7 @@ package host
 
 import (
 	"context"
+	"fmt"
 
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -25,18 +26,74 @@ func (c *Client) GetDisk(ctx context.Context, vmdName string) (*Disk, error) {
 		return nil, err
 	}
 
-	err = c.crClient.Delete(ctx, &vmd)
-	if err != nil {
-		return nil, err
+	return newDisk(&vmd)
+}
+
+func newDisk(vmd *v1alpha2.VirtualMachineDisk) (*Disk, error) {
+	var capacity resource.Quantity
+	switch {
+	case vmd.Status.Capacity != "":
+		var err error
+		capacity, err = resource.ParseQuantity(vmd.Status.Capacity)
+		if err != nil {
+			return nil, err
+		}
+	case vmd.Spec.PersistentVolumeClaim.Size != nil:
+		// The disk is not provisioned yet.
+		capacity = *vmd.Spec.PersistentVolumeClaim.Size
 	}
 
-	capacity, err := resource.ParseQuantity(vmd.Status.Capacity)
-	if err != nil {
-		return nil, err
+	var storageClass string
+	if vmd.Spec.PersistentVolumeClaim.StorageClassName != nil {
+		storageClass = *vmd.Spec.PersistentVolumeClaim.StorageClassName
 	}
 
 	return &Disk{
-		Name:     vmd.Name,
-		Capacity: capacity,
+		Name:         vmd.Name,
+		Capacity:     capacity,
+		StorageClass: storageClass,
+		Source:       newDiskSource(vmd.Spec.DataSource),
+		Condition:    newDiskCondition(vmd),
 	}, nil
 }
+
+func newDiskSource(dataSource *v1alpha2.VMDDataSource) *DiskSource {
+	switch {
+	case dataSource == nil:
+		return nil
+	case dataSource.Type == v1alpha2.DataSourceTypeVirtualMachineImage && dataSource.VirtualMachineImage != nil:
+		return &DiskSource{VirtualMachineImage: dataSource.VirtualMachineImage.Name}
+	case dataSource.Type == v1alpha2.DataSourceTypeClusterVirtualMachineImage && dataSource.ClusterVirtualMachineImage != nil:
+		return &DiskSource{ClusterVirtualMachineImage: dataSource.ClusterVirtualMachineImage.Name}
+	default:
+		return nil
+	}
+}
+
+func newDiskCondition(vmd *v1alpha2.VirtualMachineDisk) DiskCondition {
+	switch vmd.Status.Phase {
+	case v1alpha2.DiskFailed, v1alpha2.DiskPVCLost:
+		message := vmd.Status.FailureMessage
+		if message == "" {
+			message = vmd.Status.FailureReason
+		}
+
+		return DiskCondition{
+			Abnormal: true,
+			Message:  fmt.Sprintf("disk is %s: %s", vmd.Status.Phase, message),
+		}
+	case v1alpha2.DiskUnknown:
+		return DiskCondition{
+			Abnormal: true,
+			Message:  "disk phase is unknown",
+		}
+	case "":
+		return DiskCondition{
+			Message: "disk is not handled yet",
+		}
+	default:
+		return DiskCondition{
+			Message: fmt.Sprintf("disk is %s", vmd.Status.Phase),
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package host
+
+import (
+	"context"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/deckhouse/virtualization/api/core/v1alpha2"
+)
+
+type DiskList struct {
+	Disks []Disk
+	// Continue is a token to get the next page of disks, empty for the last page.
+	Continue string
+}
+
+// ListDisks returns a page of the disks created by the driver.
+func (c *Client) ListDisks(ctx context.Context, limit int64, continueToken string) (*DiskList, error) {
+	// The cache does not support continue tokens.
+	var vmds v1alpha2.VirtualMachineDiskList
+	err := c.apiReader.List(ctx, &vmds, &client.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{diskManagedByLabel: diskManagedByValue}),
+		Namespace:     c.namespace,
+		Limit:         limit,
+		Continue:      continueToken,
+	})
+	if err != nil {
+		if continueToken != "" && (k8serrors.IsResourceExpired(err) || k8serrors.IsBadRequest(err)) {
+			return nil, ErrInvalidContinueToken
+		}
+
+		return nil, err
+	}
+
+	disks := make([]Disk, 0, len(vmds.Items))
+	for i := range vmds.Items {
+		disk, err := newDisk(&vmds.Items[i])
+		if err != nil {
+			return nil, err
+		}
+
+		disks = append(disks, *disk)
+	}
+
+	return &DiskList{
+		Disks:    disks,
+		Continue: vmds.Continue,
+	}, nil
+}
+
+// LabelDisks labels the disks the driver created before the disks were labeled on creation,
+// so that ListDisks returns them too. The owned function tells the disks of the driver by name.
+// It returns the number of the labeled disks.
+func (c *Client) LabelDisks(ctx context.Context, owned func(name string) bool) (int, error) {
+	var vmds v1alpha2.VirtualMachineDiskList
+	err := c.apiReader.List(ctx, &vmds, &client.ListOptions{
+		Namespace: c.namespace,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var labeled int
+	for i := range vmds.Items {
+		vmd := &vmds.Items[i]
+		if _, ok := vmd.Labels[diskManagedByLabel]; ok || !owned(vmd.Name) {
+			continue
+		}
+
+		patch := client.MergeFrom(vmd.DeepCopy())
+		if vmd.Labels == nil {
+			vmd.Labels = make(map[string]string)
+		}
+		vmd.Labels[diskManagedByLabel] = diskManagedByValue
+
+		err = c.crClient.Patch(ctx, vmd, patch)
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return labeled, err
+		}
+
+		if err == nil {
+			labeled++
+		}
+	}
+
+	return labeled, nil
+}
+
+// ListAttachedMachines returns names of the virtual machines the disks are attached to, keyed by the disk name.
+func (c *Client) ListAttachedMachines(ctx context.Context) (map[string][]string, error) {
+	var vmbdas v1alpha2.VirtualMachineBlockDeviceAttachmentList
+	err := c.crClient.List(ctx, &vmbdas, &client.ListOptions{
+		Namespace: c.namespace,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	machines := make(map[string][]string)
+	for _, vmbda := range vmbdas.Items {
+		if vmbda.Status.Phase != v1alpha2.BlockDeviceAttachmentPhaseAttached || vmbda.Spec.BlockDevice.VirtualMachineDisk == nil {
+			continue
+		}
+
+		vmdName := vmbda.Spec.BlockDevice.VirtualMachineDisk.Name
+		machines[vmdName] = append(machines[vmdName], vmbda.Spec.VMName)
+	}
+
+	return machines, nil
+}
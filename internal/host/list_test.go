@@ -0,0 +1,178 @@
+package host
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"testing"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/deckhouse/virtualization/api/core/v1alpha2"
+)
+
+// expiredContinueToken is a continue token the paging client reports as expired.
+const expiredContinueToken = "expired"
+
+// newPagingClient returns a fake client paging the disk lists as the API server does: the fake client ignores
+// the limit, so the continue token is the offset of the next page instead of an opaque one.
+func newPagingClient(t *testing.T, objs ...client.Object) client.Client {
+	t.Helper()
+
+	scheme := runtime.NewScheme()
+	err := v1alpha2.AddToScheme(scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return interceptor.NewClient(fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build(), interceptor.Funcs{
+		List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+			vmds, ok := list.(*v1alpha2.VirtualMachineDiskList)
+			if !ok {
+				return c.List(ctx, list, opts...)
+			}
+
+			listOpts := &client.ListOptions{}
+			listOpts.ApplyOptions(opts)
+
+			var offset int
+			switch listOpts.Continue {
+			case "":
+			case expiredContinueToken:
+				return k8serrors.NewResourceExpired("continue token is too old")
+			default:
+				var err error
+				offset, err = strconv.Atoi(listOpts.Continue)
+				if err != nil {
+					return k8serrors.NewBadRequest("invalid continue token")
+				}
+			}
+
+			err := c.List(ctx, vmds, &client.ListOptions{
+				LabelSelector: listOpts.LabelSelector,
+				Namespace:     listOpts.Namespace,
+			})
+			if err != nil {
+				return err
+			}
+
+			sort.Slice(vmds.Items, func(i, j int) bool {
+				return vmds.Items[i].Name < vmds.Items[j].Name
+			})
+
+			vmds.Items = vmds.Items[min(offset, len(vmds.Items)):]
+			if listOpts.Limit > 0 && int64(len(vmds.Items)) > listOpts.Limit {
+				vmds.Items = vmds.Items[:listOpts.Limit]
+				vmds.Continue = strconv.Itoa(offset + int(listOpts.Limit))
+			}
+
+			return nil
+		},
+	})
+}
+
+func newTestManagedDisk(name string) *v1alpha2.VirtualMachineDisk {
+	vmd := newTestDisk(name)
+	vmd.Labels = map[string]string{diskManagedByLabel: diskManagedByValue}
+
+	return vmd
+}
+
+func TestListDisksPages(t *testing.T) {
+	apiClient := newPagingClient(t,
+		newTestManagedDisk("pvc-1"),
+		newTestManagedDisk("pvc-2"),
+		newTestManagedDisk("pvc-3"),
+		newTestDisk("other"),
+		newTestManagedDisk("pvc-4"),
+		newTestManagedDisk("pvc-5"),
+	)
+
+	c := &Client{
+		crClient:  apiClient,
+		apiReader: apiClient,
+		namespace: "test",
+	}
+
+	var pages [][]string
+	var continueToken string
+	for {
+		disks, err := c.ListDisks(context.Background(), 2, continueToken)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var page []string
+		for _, disk := range disks.Disks {
+			page = append(page, disk.Name)
+		}
+		pages = append(pages, page)
+
+		continueToken = disks.Continue
+		if continueToken == "" {
+			break
+		}
+
+		if len(pages) > 3 {
+			t.Fatalf("expected 3 pages, got more: %v", pages)
+		}
+	}
+
+	expected := [][]string{{"pvc-1", "pvc-2"}, {"pvc-3", "pvc-4"}, {"pvc-5"}}
+	if len(pages) != len(expected) {
+		t.Fatalf("expected pages %v, got %v", expected, pages)
+	}
+
+	for i := range expected {
+		if len(pages[i]) != len(expected[i]) {
+			t.Fatalf("expected pages %v, got %v", expected, pages)
+		}
+
+		for j := range expected[i] {
+			if pages[i][j] != expected[i][j] {
+				t.Fatalf("expected pages %v, got %v", expected, pages)
+			}
+		}
+	}
+}
+
+func TestListDisksWithoutLimit(t *testing.T) {
+	apiClient := newPagingClient(t, newTestManagedDisk("pvc-1"), newTestManagedDisk("pvc-2"), newTestDisk("other"))
+
+	c := &Client{
+		crClient:  apiClient,
+		apiReader: apiClient,
+		namespace: "test",
+	}
+
+	disks, err := c.ListDisks(context.Background(), 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(disks.Disks) != 2 || disks.Continue != "" {
+		t.Fatalf("expected the 2 managed disks on one page, got %+v", disks)
+	}
+}
+
+func TestListDisksInvalidContinueToken(t *testing.T) {
+	apiClient := newPagingClient(t, newTestManagedDisk("pvc-1"))
+
+	c := &Client{
+		crClient:  apiClient,
+		apiReader: apiClient,
+		namespace: "test",
+	}
+
+	for _, token := range []string{expiredContinueToken, "invalid"} {
+		_, err := c.ListDisks(context.Background(), 2, token)
+		if !errors.Is(err, ErrInvalidContinueToken) {
+			t.Fatalf("expected an invalid continue token for %q, got %v", token, err)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package host
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/deckhouse/dvp-csi-driver/internal/metrics"
+)
+
+// Operations the host objects are waited for.
+const (
+	waitOperationCreate = "create"
+	waitOperationDelete = "delete"
+	waitOperationAttach = "attach"
+	waitOperationDetach = "detach"
+	waitOperationUpdate = "update"
+)
+
+// metricsRoundTripper records the metrics of the requests to the host API server,
+// including the ones made by the shared informers.
+type metricsRoundTripper struct {
+	next http.RoundTripper
+}
+
+func newMetricsRoundTripper(next http.RoundTripper) http.RoundTripper {
+	return &metricsRoundTripper{next: next}
+}
+
+func (rt *metricsRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	verb, resource := parseRequest(req)
+
+	start := time.Now()
+	res, err := rt.next.RoundTrip(req)
+
+	var code int
+	if err == nil {
+		code = res.StatusCode
+	}
+
+	metrics.ObserveHostRequest(verb, resource, code, time.Since(start))
+
+	return res, err
+}
+
+// parseRequest returns the Kubernetes verb and resource of the request to the API server:
+// /api/v1/namespaces/{namespace}/{resource}/{name}/{subresource} or /apis/{group}/{version}/...
+func parseRequest(req *http.Request) (string, string) {
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+
+	switch {
+	case len(parts) >= 2 && parts[0] == "api":
+		parts = parts[2:]
+	case len(parts) >= 3 && parts[0] == "apis":
+		parts = parts[3:]
+	default:
+		return strings.ToLower(req.Method), "unknown"
+	}
+
+	if len(parts) >= 2 && parts[0] == "namespaces" {
+		parts = parts[2:]
+	}
+
+	resource := "unknown"
+	if len(parts) > 0 {
+		resource = parts[0]
+	}
+	if len(parts) > 2 {
+		resource += "/" + parts[2]
+	}
+
+	named := len(parts) > 1
+
+	switch req.Method {
+	case http.MethodGet:
+		switch {
+		case req.URL.Query().Get("watch") == "true":
+			return "watch", resource
+		case named:
+			return "get", resource
+		default:
+			return "list", resource
+		}
+	case http.MethodPost:
+		return "create", resource
+	case http.MethodPut:
+		return "update", resource
+	case http.MethodPatch:
+		return "patch", resource
+	case http.MethodDelete:
+		if named {
+			return "delete", resource
+		}
+
+		return "deletecollection", resource
+	default:
+		return strings.ToLower(req.Method), resource
+	}
+}
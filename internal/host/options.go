@@ -0,0 +1,38 @@
+package host
+
+import "log/slog"
+
+type Option interface{}
+
+// CachedReadsOption makes the client read the host objects from the shared informer cache.
+type CachedReadsOption struct{}
+
+func NewCachedReadsOption() *CachedReadsOption {
+	return &CachedReadsOption{}
+}
+
+// WithoutInformersOption makes the client read from the API server only and start no informers.
+// Such a client cannot wait for the host objects.
+type WithoutInformersOption struct{}
+
+func NewWithoutInformersOption() *WithoutInformersOption {
+	return &WithoutInformersOption{}
+}
+
+// KubeconfigFileOption makes the client read the kubeconfig from the file, e.g. a mounted Secret,
+// instead of the HOST_KUBECONFIG env, and reload the credentials every time the file changes.
+type KubeconfigFileOption struct {
+	Path string
+}
+
+func NewKubeconfigFileOption(path string) *KubeconfigFileOption {
+	return &KubeconfigFileOption{Path: path}
+}
+
+type LoggerOption struct {
+	Logger *slog.Logger
+}
+
+func NewLoggerOption(logger *slog.Logger) *LoggerOption {
+	return &LoggerOption{Logger: logger}
+}
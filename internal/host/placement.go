@@ -0,0 +1,79 @@
+package host
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/deckhouse/virtualization/api/core/v1alpha2"
+)
+
+// Placement is where the virtual machine runs on the host cluster.
+type Placement struct {
+	// Node is a name of the host node, empty if the virtual machine is not running.
+	Node string
+	// Zone is a zone the virtual machine is pinned to, empty if it can run in any zone.
+	Zone string
+}
+
+// GetMachinePlacement returns the placement of the virtual machine. The zone is read from the virtual machine
+// itself rather than from the host node, so that the credentials need no access to the cluster-wide host nodes.
+func (c *Client) GetMachinePlacement(ctx context.Context, vmName string) (*Placement, error) {
+	var vm v1alpha2.VirtualMachine
+
+	err := c.apiReader.Get(ctx, types.NamespacedName{
+		Namespace: c.namespace,
+		Name:      vmName,
+	}, &vm)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, ErrMachineNotFound
+		}
+
+		return nil, err
+	}
+
+	return &Placement{
+		Node: vm.Status.NodeName,
+		Zone: machineZone(&vm),
+	}, nil
+}
+
+// machineZone returns the zone the virtual machine is pinned to by its node selector, or by the required
+// node affinity allowing a single zone in every term. It returns an empty zone if the virtual machine
+// can run, and thus migrate, in several zones.
+func machineZone(vm *v1alpha2.VirtualMachine) string {
+	zone := vm.Spec.NodeSelector[corev1.LabelTopologyZone]
+	if zone != "" {
+		return zone
+	}
+
+	if vm.Spec.Affinity == nil || vm.Spec.Affinity.NodeAffinity == nil ||
+		vm.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return ""
+	}
+
+	for _, term := range vm.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		termZone := nodeSelectorTermZone(term)
+		if termZone == "" || (zone != "" && termZone != zone) {
+			return ""
+		}
+
+		zone = termZone
+	}
+
+	return zone
+}
+
+// nodeSelectorTermZone returns the single zone the node selector term allows, if any.
+func nodeSelectorTermZone(term corev1.NodeSelectorTerm) string {
+	for _, expression := range term.MatchExpressions {
+		if expression.Key == corev1.LabelTopologyZone && expression.Operator == corev1.NodeSelectorOpIn && len(expression.Values) == 1 {
+			return expression.Values[0]
+		}
+	}
+
+	return ""
+}
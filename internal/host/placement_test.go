@@ -0,0 +1,122 @@
+package host
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/deckhouse/virtualization/api/core/v1alpha2"
+)
+
+func zoneTerm(zones ...string) corev1.NodeSelectorTerm {
+	return corev1.NodeSelectorTerm{
+		MatchExpressions: []corev1.NodeSelectorRequirement{
+			{Key: "kubernetes.io/arch", Operator: corev1.NodeSelectorOpIn, Values: []string{"amd64"}},
+			{Key: corev1.LabelTopologyZone, Operator: corev1.NodeSelectorOpIn, Values: zones},
+		},
+	}
+}
+
+func zoneAffinity(terms ...corev1.NodeSelectorTerm) *v1alpha2.VMAffinity {
+	return &v1alpha2.VMAffinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: terms,
+			},
+		},
+	}
+}
+
+func TestMachineZone(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     v1alpha2.VirtualMachineSpec
+		expected string
+	}{
+		{
+			name: "not pinned",
+		},
+		{
+			name: "node selector",
+			spec: v1alpha2.VirtualMachineSpec{
+				NodeSelector: map[string]string{corev1.LabelTopologyZone: "zone-a"},
+			},
+			expected: "zone-a",
+		},
+		{
+			name: "node affinity",
+			spec: v1alpha2.VirtualMachineSpec{
+				Affinity: zoneAffinity(zoneTerm("zone-a"), zoneTerm("zone-a")),
+			},
+			expected: "zone-a",
+		},
+		{
+			name: "node affinity of several zones",
+			spec: v1alpha2.VirtualMachineSpec{
+				Affinity: zoneAffinity(zoneTerm("zone-a", "zone-b")),
+			},
+		},
+		{
+			name: "node affinity terms of different zones",
+			spec: v1alpha2.VirtualMachineSpec{
+				Affinity: zoneAffinity(zoneTerm("zone-a"), zoneTerm("zone-b")),
+			},
+		},
+		{
+			name: "node affinity term without zone",
+			spec: v1alpha2.VirtualMachineSpec{
+				Affinity: zoneAffinity(zoneTerm("zone-a"), corev1.NodeSelectorTerm{}),
+			},
+		},
+		{
+			name: "preferred node affinity",
+			spec: v1alpha2.VirtualMachineSpec{
+				Affinity: &v1alpha2.VMAffinity{
+					NodeAffinity: &corev1.NodeAffinity{
+						PreferredDuringSchedulingIgnoredDuringExecution: []corev1.PreferredSchedulingTerm{
+							{Weight: 1, Preference: zoneTerm("zone-a")},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zone := machineZone(&v1alpha2.VirtualMachine{Spec: tt.spec})
+			if zone != tt.expected {
+				t.Fatalf("expected zone %q, got %q", tt.expected, zone)
+			}
+		})
+	}
+}
+
+func TestGetMachinePlacement(t *testing.T) {
+	vm := &v1alpha2.VirtualMachine{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1", Namespace: "test"},
+		Spec: v1alpha2.VirtualMachineSpec{
+			NodeSelector: map[string]string{corev1.LabelTopologyZone: "zone-a"},
+		},
+		Status: v1alpha2.VirtualMachineStatus{NodeName: "host-1"},
+	}
+	c, _ := newTestClient(t, []client.Object{vm}, nil)
+
+	placement, err := c.GetMachinePlacement(context.Background(), "node-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if placement.Node != "host-1" || placement.Zone != "zone-a" {
+		t.Fatalf("expected host-1 in zone-a, got %+v", placement)
+	}
+
+	_, err = c.GetMachinePlacement(context.Background(), "node-2")
+	if !errors.Is(err, ErrMachineNotFound) {
+		t.Fatalf("expected machine not found, got %v", err)
+	}
+}
@@ -0,0 +1,44 @@
+package host
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/deckhouse/dvp-csi-driver/internal/tracing"
+)
+
+// tracingRoundTripper starts a span for the request to the host API server made within a traced call.
+// The requests of the shared informers have no parent span and are not traced.
+type tracingRoundTripper struct {
+	next http.RoundTripper
+}
+
+func newTracingRoundTripper(next http.RoundTripper) http.RoundTripper {
+	return &tracingRoundTripper{next: next}
+}
+
+func (rt *tracingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !trace.SpanContextFromContext(req.Context()).IsValid() {
+		return rt.next.RoundTrip(req)
+	}
+
+	verb, resource := parseRequest(req)
+
+	ctx, span := tracing.Start(req.Context(), "host "+verb+" "+resource,
+		attribute.String("k8s.verb", verb),
+		attribute.String("k8s.resource", resource),
+		semconv.HTTPMethod(req.Method),
+	)
+
+	res, err := rt.next.RoundTrip(req.WithContext(ctx))
+	if err == nil {
+		span.SetAttributes(semconv.HTTPStatusCode(res.StatusCode))
+	}
+
+	tracing.End(span, err)
+
+	return res, err
+}
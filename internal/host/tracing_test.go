@@ -0,0 +1,81 @@
+package host
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/deckhouse/dvp-csi-driver/internal/tracing"
+	"github.com/deckhouse/dvp-csi-driver/internal/tracing/tracingtest"
+	"github.com/deckhouse/virtualization/api/core/v1alpha2"
+)
+
+func TestWaitSpans(t *testing.T) {
+	spans := tracingtest.Record(t)
+
+	c, _ := newTestClient(t, nil, []client.Object{newTestDiskInPhase("disk", v1alpha2.DiskReady)})
+
+	ctx, rpc := tracing.Start(context.Background(), "/csi.v1.Controller/CreateVolume")
+	err := c.Wait(ctx, waitOperationCreate, "disk", &v1alpha2.VirtualMachineDisk{}, isDiskReady)
+	tracing.End(rpc, err)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorded := spans()
+	rpcSpan := tracingtest.FindSpan(t, recorded, "/csi.v1.Controller/CreateVolume")
+	waitSpan := tracingtest.FindSpan(t, recorded, "host.Wait")
+	checkSpan := tracingtest.FindSpan(t, recorded, "host.Wait.check")
+
+	tracingtest.ExpectChild(t, waitSpan, rpcSpan)
+	tracingtest.ExpectChild(t, checkSpan, waitSpan)
+}
+
+func TestTracingRoundTripperSpans(t *testing.T) {
+	spans := tracingtest.Record(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	rt := newTracingRoundTripper(http.DefaultTransport)
+	url := server.URL + "/apis/virtualization.deckhouse.io/v1alpha2/namespaces/test/virtualmachinedisks/disk"
+
+	get := func(ctx context.Context) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := rt.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = res.Body.Close()
+	}
+
+	// The requests of the informers have no parent span.
+	get(context.Background())
+
+	ctx, rpc := tracing.Start(context.Background(), "/csi.v1.Controller/ControllerGetVolume")
+	get(ctx)
+	tracing.End(rpc, nil)
+
+	recorded := spans()
+	if len(recorded) != 2 {
+		t.Fatalf("expected the request and the call spans only, got %d", len(recorded))
+	}
+
+	requestSpan := tracingtest.FindSpan(t, recorded, "host get virtualmachinedisks")
+	tracingtest.ExpectChild(t, requestSpan, tracingtest.FindSpan(t, recorded, "/csi.v1.Controller/ControllerGetVolume"))
+
+	for _, attr := range requestSpan.Attributes {
+		if attr.Key == "http.status_code" && attr.Value.AsInt64() != http.StatusOK {
+			t.Fatalf("expected status code 200, got %d", attr.Value.AsInt64())
+		}
+	}
+}
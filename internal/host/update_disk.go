@@ -2,9 +2,13 @@ package host
 
 import (
 	"context"
+	"fmt"
 
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/deckhouse/virtualization/api/core/v1alpha2"
 )
@@ -29,3 +33,99 @@ func (c *Client) UpdateDiskCapacity(ctx context.Context, vmdName string, capacit
 
 	return nil
 }
+
+// UpdateDiskStorageClass requests the host to move the disk to another storage class.
+func (c *Client) UpdateDiskStorageClass(ctx context.Context, vmdName, storageClass string) error {
+	var vmd v1alpha2.VirtualMachineDisk
+
+	err := c.crClient.Get(ctx, types.NamespacedName{
+		Namespace: c.namespace,
+		Name:      vmdName,
+	}, &vmd)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return ErrDiskNotFound
+		}
+
+		return err
+	}
+
+	if vmd.Spec.PersistentVolumeClaim.StorageClassName != nil && *vmd.Spec.PersistentVolumeClaim.StorageClassName == storageClass {
+		return nil
+	}
+
+	// The patch carries no resource version, so a disk read from a stale cache does not conflict.
+	patch := client.MergeFrom(vmd.DeepCopy())
+	vmd.Spec.PersistentVolumeClaim.StorageClassName = &storageClass
+
+	err = c.crClient.Patch(ctx, &vmd, patch)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return ErrDiskNotFound
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// ProgressFn is called with the phase and the progress of the disk every time they change.
+type ProgressFn func(phase, progress string)
+
+// WaitDiskUpdating waits until the disk is ready on the host storage class. The disk stays Ready
+// for a while after the update, so the storage class of its target PVC is checked as well,
+// on the changes of the PVC too.
+func (c *Client) WaitDiskUpdating(ctx context.Context, vmdName, storageClass string, progressFn ProgressFn) error {
+	var phase, progress string
+
+	return c.waitWatching(ctx, waitOperationUpdate, vmdName, &v1alpha2.VirtualMachineDisk{}, func(obj client.Object, watch watchFn) (bool, error) {
+		if obj == nil {
+			return false, ErrDiskNotFound
+		}
+
+		vmd, ok := obj.(*v1alpha2.VirtualMachineDisk)
+		if !ok {
+			return false, fmt.Errorf("expected a VirtualMachineDisk but got a %T", obj)
+		}
+
+		if string(vmd.Status.Phase) != phase || vmd.Status.Progress != progress {
+			phase, progress = string(vmd.Status.Phase), vmd.Status.Progress
+			progressFn(phase, progress)
+		}
+
+		if vmd.Status.Phase == v1alpha2.DiskFailed {
+			return false, fmt.Errorf("disk update failed: %s", vmd.Status.FailureMessage)
+		}
+
+		if vmd.Status.Phase != v1alpha2.DiskReady || vmd.Status.Target.PersistentVolumeClaimName == "" {
+			return false, nil
+		}
+
+		// The disk may stay Ready while the PVC is replaced.
+		watch(&corev1.PersistentVolumeClaim{}, vmd.Status.Target.PersistentVolumeClaimName)
+
+		return c.isClaimOnStorageClass(ctx, vmd.Status.Target.PersistentVolumeClaimName, storageClass)
+	})
+}
+
+// isClaimOnStorageClass reports whether the host PVC is on the storage class. The claim is read
+// from the API server, as the watch event may come before the cache is updated.
+func (c *Client) isClaimOnStorageClass(ctx context.Context, pvcName, storageClass string) (bool, error) {
+	var pvc corev1.PersistentVolumeClaim
+
+	err := c.apiReader.Get(ctx, types.NamespacedName{
+		Namespace: c.namespace,
+		Name:      pvcName,
+	}, &pvc)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			// Being replaced.
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return pvc.Spec.StorageClassName != nil && *pvc.Spec.StorageClassName == storageClass, nil
+}
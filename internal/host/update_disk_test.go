@@ -0,0 +1,119 @@
+package host
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/deckhouse/virtualization/api/core/v1alpha2"
+)
+
+func newTestClaim(name, storageClass string) *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "test",
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			StorageClassName: &storageClass,
+		},
+	}
+}
+
+func newTestDiskOnClaim(name, pvcName string) *v1alpha2.VirtualMachineDisk {
+	vmd := newTestDiskInPhase(name, v1alpha2.DiskReady)
+	vmd.Status.Target.PersistentVolumeClaimName = pvcName
+
+	return vmd
+}
+
+func TestWaitDiskUpdatingOnClaimChange(t *testing.T) {
+	disk := newTestDiskOnClaim("disk", "disk-pvc")
+	claim := newTestClaim("disk-pvc", "old")
+	c, _ := newTestClient(t, []client.Object{disk, claim}, []client.Object{disk})
+
+	result := make(chan error, 1)
+	go func() {
+		result <- c.WaitDiskUpdating(context.Background(), "disk", "new", func(_, _ string) {})
+	}()
+
+	// The disk stays Ready while the claim is replaced.
+	expectNoResult(t, result)
+
+	storageClass := "new"
+	claim.Spec.StorageClassName = &storageClass
+	err := c.crClient.Update(context.Background(), claim)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.watcher.EventHandler().OnUpdate(claim, claim)
+
+	err = expectResult(t, result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(c.watcher.waiters) != 0 {
+		t.Fatal("expected the wait to stop watching the claim")
+	}
+}
+
+func TestWaitDiskUpdatingFailed(t *testing.T) {
+	disk := newTestDiskInPhase("disk", v1alpha2.DiskFailed)
+	c, _ := newTestClient(t, nil, []client.Object{disk})
+
+	err := c.WaitDiskUpdating(context.Background(), "disk", "new", func(_, _ string) {})
+	if err == nil {
+		t.Fatal("expected the failed disk to fail the wait")
+	}
+}
+
+func TestUpdateDiskStorageClassFromStaleCache(t *testing.T) {
+	c, cacheClient := newTestClient(t, []client.Object{newTestDisk("disk")}, []client.Object{newTestDisk("disk")})
+
+	// The disk changed on the API server after the cache had seen it.
+	apiClient := c.crClient
+	var vmd v1alpha2.VirtualMachineDisk
+	err := apiClient.Get(context.Background(), types.NamespacedName{Namespace: "test", Name: "disk"}, &vmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vmd.Labels = map[string]string{"changed": "true"}
+	err = apiClient.Update(context.Background(), &vmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withCachedReads(c, cacheClient)
+
+	err = c.UpdateDiskStorageClass(context.Background(), "disk", "new")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = apiClient.Get(context.Background(), types.NamespacedName{Namespace: "test", Name: "disk"}, &vmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storageClass := vmd.Spec.PersistentVolumeClaim.StorageClassName
+	if storageClass == nil || *storageClass != "new" || vmd.Labels["changed"] != "true" {
+		t.Fatalf("expected the storage class to be patched, got %+v", vmd)
+	}
+}
+
+func TestUpdateDiskStorageClassNotFound(t *testing.T) {
+	c, _ := newTestClient(t, nil, nil)
+
+	err := c.UpdateDiskStorageClass(context.Background(), "disk", "new")
+	if !errors.Is(err, ErrDiskNotFound) {
+		t.Fatalf("expected disk not found, got %v", err)
+	}
+}
@@ -2,36 +2,80 @@ package host
 
 import (
 	"context"
+	"errors"
+	"reflect"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/deckhouse/dvp-csi-driver/internal/metrics"
+	"github.com/deckhouse/dvp-csi-driver/internal/tracing"
 )
 
-const defaultWaitCheckInterval = time.Second
+// defaultWaitResyncInterval is an interval to check the object on the API server
+// in case a watch event is missed.
+const defaultWaitResyncInterval = 30 * time.Second
 
+// WaitFn checks the object, which is nil if not found.
 type WaitFn func(obj client.Object) (bool, error)
 
-func (c *Client) Wait(ctx context.Context, name string, obj client.Object, waitFn WaitFn) error {
-	var done bool
-	for {
-		err := c.crClient.Get(ctx, types.NamespacedName{
-			Namespace: c.namespace,
-			Name:      name,
-		}, obj)
-		if err != nil {
-			if !k8serrors.IsNotFound(err) {
-				return err
-			}
-
-			// obj not found.
-			done, err = waitFn(nil)
-		} else {
-			// obj found.
-			done, err = waitFn(obj)
+// watchFn makes the wait check the object on the changes of another object too, such as the one the check reads.
+type watchFn func(obj client.Object, name string)
+
+// watchingWaitFn is a WaitFn able to watch the other objects it depends on.
+type watchingWaitFn func(obj client.Object, watch watchFn) (bool, error)
+
+// Wait blocks until waitFn reports done. The object is checked every time the shared informer
+// notifies about its change and, as a fallback, on the API server every resync interval.
+// The wait duration is recorded by the operation, and every check is traced.
+func (c *Client) Wait(ctx context.Context, operation, name string, obj client.Object, waitFn WaitFn) error {
+	return c.waitWatching(ctx, operation, name, obj, func(obj client.Object, _ watchFn) (bool, error) {
+		return waitFn(obj)
+	})
+}
+
+// waitWatching is Wait checking the object on the changes of the objects watched by waitFn too.
+func (c *Client) waitWatching(ctx context.Context, operation, name string, obj client.Object, waitFn watchingWaitFn) (err error) {
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, "host.Wait",
+		attribute.String("wait.operation", operation),
+		attribute.String("k8s.name", name),
+	)
+	defer func() {
+		metrics.ObserveHostWait(operation, start, err)
+		tracing.End(span, err)
+	}()
+
+	if c.cache == nil {
+		return errors.New("cannot wait without informers")
+	}
+
+	notifications, unsubscribe := c.watcher.Subscribe(obj, name)
+	defer unsubscribe()
+
+	watched := make(map[watchKey]func())
+	defer func() {
+		for _, unwatch := range watched {
+			unwatch()
 		}
+	}()
+
+	watch := func(obj client.Object, name string) {
+		key := watchKey{kind: reflect.TypeOf(obj), name: name}
+		if _, ok := watched[key]; !ok {
+			watched[key] = c.watcher.Add(notifications, obj, name)
+		}
+	}
 
+	fromCache := true
+
+	for {
+		done, err := c.check(ctx, fromCache, name, obj, func(obj client.Object) (bool, error) {
+			return waitFn(obj, watch)
+		})
 		if err != nil {
 			return err
 		}
@@ -40,13 +84,53 @@ func (c *Client) Wait(ctx context.Context, name string, obj client.Object, waitF
 			return nil
 		}
 
-		timer := time.NewTimer(defaultWaitCheckInterval)
+		timer := time.NewTimer(c.resyncInterval)
 
 		select {
+		case <-notifications:
+			timer.Stop()
+			fromCache = true
 		case <-timer.C:
+			fromCache = false
 		case <-ctx.Done():
 			timer.Stop()
 			return ctx.Err()
 		}
 	}
 }
+
+// check reads the object from the cache or from the API server and checks it with waitFn.
+func (c *Client) check(ctx context.Context, fromCache bool, name string, obj client.Object, waitFn WaitFn) (done bool, err error) {
+	var reader client.Reader = c.apiReader
+	if fromCache {
+		reader = c.cache
+	}
+
+	ctx, span := tracing.Start(ctx, "host.Wait.check", attribute.Bool("wait.from_cache", fromCache))
+	defer func() {
+		span.SetAttributes(attribute.Bool("wait.done", done))
+		tracing.End(span, err)
+	}()
+
+	err = reader.Get(ctx, types.NamespacedName{
+		Namespace: c.namespace,
+		Name:      name,
+	}, obj)
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return false, err
+		}
+
+		if fromCache {
+			// The informer may not have seen the object just created yet: for the deletion and detaching waits,
+			// the object not found means done, so it is confirmed on the API server.
+			return c.check(ctx, false, name, obj, waitFn)
+		}
+
+		// obj not found.
+		return waitFn(nil)
+	}
+
+	// obj found.
+	return waitFn(obj)
+}
@@ -0,0 +1,242 @@
+package host
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/deckhouse/virtualization/api/core/v1alpha2"
+)
+
+// testCache is a cache reading the objects from the fake client.
+type testCache struct {
+	cache.Cache
+	reader client.Reader
+}
+
+func (c *testCache) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	return c.reader.Get(ctx, key, obj, opts...)
+}
+
+func (c *testCache) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	return c.reader.List(ctx, list, opts...)
+}
+
+// newTestClient returns a client with the API server and the cache backed by separate fake clients,
+// so that the cache can lag behind the API server.
+func newTestClient(t *testing.T, server, cached []client.Object) (*Client, client.Client) {
+	t.Helper()
+
+	scheme := runtime.NewScheme()
+	err := v1alpha2.AddToScheme(scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = corev1.AddToScheme(scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	apiClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(server...).Build()
+	cacheClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cached...).Build()
+
+	return &Client{
+		crClient:       apiClient,
+		apiReader:      apiClient,
+		cache:          &testCache{reader: cacheClient},
+		watcher:        newWatcher(),
+		namespace:      "test",
+		resyncInterval: time.Hour,
+	}, cacheClient
+}
+
+func newTestDiskInPhase(name string, phase v1alpha2.DiskPhase) *v1alpha2.VirtualMachineDisk {
+	vmd := newTestDisk(name)
+	vmd.Status.Phase = phase
+
+	return vmd
+}
+
+func isDiskReady(obj client.Object) (bool, error) {
+	if obj == nil {
+		return false, nil
+	}
+
+	return obj.(*v1alpha2.VirtualMachineDisk).Status.Phase == v1alpha2.DiskReady, nil
+}
+
+// waitAsync runs the wait in the background and returns the channel receiving its result.
+func waitAsync(ctx context.Context, c *Client, waitFn WaitFn) <-chan error {
+	result := make(chan error, 1)
+	go func() {
+		result <- c.Wait(ctx, waitOperationCreate, "disk", &v1alpha2.VirtualMachineDisk{}, waitFn)
+	}()
+
+	return result
+}
+
+func expectResult(t *testing.T, result <-chan error) error {
+	t.Helper()
+
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("wait has not returned")
+		return nil
+	}
+}
+
+func expectNoResult(t *testing.T, result <-chan error) {
+	t.Helper()
+
+	select {
+	case err := <-result:
+		t.Fatalf("wait has returned unexpectedly: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestWaitReturnsIfDone(t *testing.T) {
+	c, _ := newTestClient(t, nil, []client.Object{newTestDiskInPhase("disk", v1alpha2.DiskReady)})
+
+	err := expectResult(t, waitAsync(context.Background(), c, isDiskReady))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWaitChecksOnNotification(t *testing.T) {
+	disk := newTestDiskInPhase("disk", v1alpha2.DiskProvisioning)
+	c, cacheClient := newTestClient(t, nil, []client.Object{disk})
+
+	result := waitAsync(context.Background(), c, isDiskReady)
+	expectNoResult(t, result)
+
+	disk.Status.Phase = v1alpha2.DiskReady
+	err := cacheClient.Update(context.Background(), disk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.watcher.EventHandler().OnUpdate(disk, disk)
+
+	err = expectResult(t, result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(c.watcher.waiters) != 0 {
+		t.Fatal("expected the wait to unsubscribe")
+	}
+}
+
+func TestWaitChecksDeletionOnTombstone(t *testing.T) {
+	disk := newTestDiskInPhase("disk", v1alpha2.DiskReady)
+	c, cacheClient := newTestClient(t, nil, []client.Object{disk})
+
+	result := waitAsync(context.Background(), c, func(obj client.Object) (bool, error) {
+		return obj == nil, nil
+	})
+	expectNoResult(t, result)
+
+	err := cacheClient.Delete(context.Background(), disk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.watcher.EventHandler().OnDelete(toolscache.DeletedFinalStateUnknown{Key: "test/disk", Obj: disk})
+
+	err = expectResult(t, result)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWaitFallsBackToResync(t *testing.T) {
+	// The cache missed the update seen by the API server.
+	c, _ := newTestClient(t,
+		[]client.Object{newTestDiskInPhase("disk", v1alpha2.DiskReady)},
+		[]client.Object{newTestDiskInPhase("disk", v1alpha2.DiskProvisioning)},
+	)
+	c.resyncInterval = 10 * time.Millisecond
+
+	err := expectResult(t, waitAsync(context.Background(), c, isDiskReady))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWaitReturnsCheckError(t *testing.T) {
+	c, _ := newTestClient(t, nil, []client.Object{newTestDiskInPhase("disk", v1alpha2.DiskFailed)})
+
+	errFailed := errors.New("failed")
+
+	err := expectResult(t, waitAsync(context.Background(), c, func(obj client.Object) (bool, error) {
+		return false, errFailed
+	}))
+	if !errors.Is(err, errFailed) {
+		t.Fatalf("expected the check error, got %v", err)
+	}
+}
+
+func TestWaitUnsubscribesOnCancel(t *testing.T) {
+	c, _ := newTestClient(t, nil, []client.Object{newTestDiskInPhase("disk", v1alpha2.DiskProvisioning)})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	result := waitAsync(ctx, c, isDiskReady)
+	expectNoResult(t, result)
+
+	cancel()
+
+	err := expectResult(t, result)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context canceled, got %v", err)
+	}
+
+	if len(c.watcher.waiters) != 0 {
+		t.Fatal("expected the wait to unsubscribe")
+	}
+}
+
+func TestWaitRequiresInformers(t *testing.T) {
+	c, _ := newTestClient(t, nil, nil)
+	c.cache = nil
+
+	err := c.Wait(context.Background(), waitOperationCreate, "disk", &v1alpha2.VirtualMachineDisk{}, isDiskReady)
+	if err == nil {
+		t.Fatal("expected an error without informers")
+	}
+}
+
+func TestWaitConfirmsNotFoundOnAPIServer(t *testing.T) {
+	// The disk has just been created: the API server has it, the cache has not seen it yet.
+	c, _ := newTestClient(t, []client.Object{newTestDiskInPhase("disk", v1alpha2.DiskReady)}, nil)
+
+	result := waitAsync(context.Background(), c, func(obj client.Object) (bool, error) {
+		return obj == nil, nil
+	})
+	expectNoResult(t, result)
+
+	err := c.crClient.Delete(context.Background(), newTestDisk("disk"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.watcher.EventHandler().OnDelete(newTestDisk("disk"))
+
+	err = expectResult(t, result)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,95 @@
+package host
+
+import (
+	"reflect"
+	"sync"
+
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// watcher notifies the waiters about the changes of the host objects received from the shared informers.
+type watcher struct {
+	mu      sync.Mutex
+	waiters map[watchKey]map[chan struct{}]struct{}
+}
+
+type watchKey struct {
+	kind reflect.Type
+	name string
+}
+
+func newWatcher() *watcher {
+	return &watcher{
+		waiters: make(map[watchKey]map[chan struct{}]struct{}),
+	}
+}
+
+// Subscribe returns a channel receiving a value when the object of the same kind with the name changes,
+// and a function to unsubscribe.
+func (w *watcher) Subscribe(obj client.Object, name string) (chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	return ch, w.Add(ch, obj, name)
+}
+
+// Add makes the subscribed channel receive a value when another object changes too,
+// and returns a function to stop it.
+func (w *watcher) Add(ch chan struct{}, obj client.Object, name string) func() {
+	key := watchKey{kind: reflect.TypeOf(obj), name: name}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.waiters[key] == nil {
+		w.waiters[key] = make(map[chan struct{}]struct{})
+	}
+	w.waiters[key][ch] = struct{}{}
+
+	return func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		delete(w.waiters[key], ch)
+		if len(w.waiters[key]) == 0 {
+			delete(w.waiters, key)
+		}
+	}
+}
+
+// EventHandler returns an informer event handler notifying the waiters.
+func (w *watcher) EventHandler() toolscache.ResourceEventHandler {
+	return toolscache.ResourceEventHandlerFuncs{
+		AddFunc: w.notify,
+		UpdateFunc: func(_, obj interface{}) {
+			w.notify(obj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+
+			w.notify(obj)
+		},
+	}
+}
+
+func (w *watcher) notify(obj interface{}) {
+	o, ok := obj.(client.Object)
+	if !ok {
+		return
+	}
+
+	key := watchKey{kind: reflect.TypeOf(o), name: o.GetName()}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for ch := range w.waiters[key] {
+		select {
+		case ch <- struct{}{}:
+		default:
+			// The waiter has a pending notification already.
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package host
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	toolscache "k8s.io/client-go/tools/cache"
+
+	"github.com/deckhouse/virtualization/api/core/v1alpha2"
+)
+
+func newTestDisk(name string) *v1alpha2.VirtualMachineDisk {
+	return &v1alpha2.VirtualMachineDisk{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "test",
+		},
+	}
+}
+
+func isNotified(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestWatcherNotifiesOnChange(t *testing.T) {
+	tests := []struct {
+		name   string
+		handle func(handler toolscache.ResourceEventHandler)
+	}{
+		{
+			name: "add",
+			handle: func(handler toolscache.ResourceEventHandler) {
+				handler.OnAdd(newTestDisk("disk"), false)
+			},
+		},
+		{
+			name: "update",
+			handle: func(handler toolscache.ResourceEventHandler) {
+				handler.OnUpdate(newTestDisk("disk"), newTestDisk("disk"))
+			},
+		},
+		{
+			name: "delete",
+			handle: func(handler toolscache.ResourceEventHandler) {
+				handler.OnDelete(newTestDisk("disk"))
+			},
+		},
+		{
+			name: "tombstone delete",
+			handle: func(handler toolscache.ResourceEventHandler) {
+				handler.OnDelete(toolscache.DeletedFinalStateUnknown{
+					Key: "test/disk",
+					Obj: newTestDisk("disk"),
+				})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newWatcher()
+
+			notifications, unsubscribe := w.Subscribe(&v1alpha2.VirtualMachineDisk{}, "disk")
+			defer unsubscribe()
+
+			tt.handle(w.EventHandler())
+
+			if !isNotified(notifications) {
+				t.Fatal("expected a notification")
+			}
+		})
+	}
+}
+
+func TestWatcherIgnoresOtherObjects(t *testing.T) {
+	w := newWatcher()
+
+	notifications, unsubscribe := w.Subscribe(&v1alpha2.VirtualMachineDisk{}, "disk")
+	defer unsubscribe()
+
+	handler := w.EventHandler()
+	handler.OnAdd(newTestDisk("other"), false)
+	handler.OnAdd(&v1alpha2.VirtualMachineBlockDeviceAttachment{
+		ObjectMeta: metav1.ObjectMeta{Name: "disk"},
+	}, false)
+	handler.OnDelete(toolscache.DeletedFinalStateUnknown{Key: "test/disk"})
+
+	if isNotified(notifications) {
+		t.Fatal("expected no notification")
+	}
+}
+
+func TestWatcherCoalescesNotifications(t *testing.T) {
+	w := newWatcher()
+
+	notifications, unsubscribe := w.Subscribe(&v1alpha2.VirtualMachineDisk{}, "disk")
+	defer unsubscribe()
+
+	handler := w.EventHandler()
+	handler.OnAdd(newTestDisk("disk"), false)
+	handler.OnUpdate(newTestDisk("disk"), newTestDisk("disk"))
+
+	if !isNotified(notifications) {
+		t.Fatal("expected a notification")
+	}
+
+	if isNotified(notifications) {
+		t.Fatal("expected the notifications to be coalesced")
+	}
+}
+
+func TestWatcherUnsubscribe(t *testing.T) {
+	w := newWatcher()
+
+	first, unsubscribeFirst := w.Subscribe(&v1alpha2.VirtualMachineDisk{}, "disk")
+	second, unsubscribeSecond := w.Subscribe(&v1alpha2.VirtualMachineDisk{}, "disk")
+	defer unsubscribeSecond()
+
+	unsubscribeFirst()
+	w.EventHandler().OnAdd(newTestDisk("disk"), false)
+
+	if isNotified(first) {
+		t.Fatal("expected no notification after unsubscribe")
+	}
+
+	if !isNotified(second) {
+		t.Fatal("expected a notification of the remaining waiter")
+	}
+
+	unsubscribeSecond()
+
+	if len(w.waiters) != 0 {
+		t.Fatalf("expected no waiters, got %d", len(w.waiters))
+	}
+}
@@ -0,0 +1,136 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc/status"
+)
+
+const namespace = "dvp_csi"
+
+// Results of the observed operations.
+const (
+	resultSuccess = "success"
+	resultError   = "error"
+)
+
+var registry = prometheus.NewRegistry()
+
+var (
+	operationsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "operations_total",
+		Help:      "Number of the CSI calls by method and gRPC code.",
+	}, []string{"method", "code"})
+
+	operationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "operation_duration_seconds",
+		Help:      "Duration of the CSI calls by method and gRPC code.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 16),
+	}, []string{"method", "code"})
+
+	operationsInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "operations_in_flight",
+		Help:      "Number of the CSI calls in progress by method.",
+	}, []string{"method"})
+
+	hostRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "host_requests_total",
+		Help:      "Number of the requests to the host API server by verb, resource and HTTP code.",
+	}, []string{"verb", "resource", "code"})
+
+	hostRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "host_request_duration_seconds",
+		Help:      "Duration of the requests to the host API server by verb and resource, watches excluded.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"verb", "resource"})
+
+	hostWaitDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "host_wait_duration_seconds",
+		Help:      "Duration of the waits for the host objects by operation and result.",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 2, 14),
+	}, []string{"operation", "result"})
+
+	mountOperationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "mount_operation_duration_seconds",
+		Help:      "Duration of the mount, format and resize operations on the node by operation and result.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 14),
+	}, []string{"operation", "result"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		operationsTotal,
+		operationDuration,
+		operationsInFlight,
+		hostRequestsTotal,
+		hostRequestDuration,
+		hostWaitDuration,
+		mountOperationDuration,
+	)
+}
+
+// Handler returns the handler exposing the metrics in the Prometheus format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// StartOperation counts the CSI call in progress. The returned function records its result.
+func StartOperation(method string) func(err error) {
+	start := time.Now()
+	operationsInFlight.WithLabelValues(method).Inc()
+
+	return func(err error) {
+		operationsInFlight.WithLabelValues(method).Dec()
+
+		code := status.Code(err).String()
+		operationsTotal.WithLabelValues(method, code).Inc()
+		operationDuration.WithLabelValues(method, code).Observe(time.Since(start).Seconds())
+	}
+}
+
+// ObserveHostRequest records the request to the host API server. The code is 0 if no response is received.
+func ObserveHostRequest(verb, resource string, code int, duration time.Duration) {
+	codeLabel := resultError
+	if code != 0 {
+		codeLabel = strconv.Itoa(code)
+	}
+
+	hostRequestsTotal.WithLabelValues(verb, resource, codeLabel).Inc()
+
+	// A watch lasts until it is closed, so its duration tells nothing about the API server.
+	if verb != "watch" {
+		hostRequestDuration.WithLabelValues(verb, resource).Observe(duration.Seconds())
+	}
+}
+
+// ObserveHostWait records the wait for the host object started at the given time.
+func ObserveHostWait(operation string, start time.Time, err error) {
+	hostWaitDuration.WithLabelValues(operation, result(err)).Observe(time.Since(start).Seconds())
+}
+
+// ObserveMountOperation records the node operation started at the given time.
+func ObserveMountOperation(operation string, start time.Time, err error) {
+	mountOperationDuration.WithLabelValues(operation, result(err)).Observe(time.Since(start).Seconds())
+}
+
+func result(err error) string {
+	if err != nil {
+		return resultError
+	}
+
+	return resultSuccess
+}
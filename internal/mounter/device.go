@@ -0,0 +1,178 @@
+package mounter
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// VirtioSerialMaxLength is a length virtio-blk truncates the disk serials to.
+const VirtioSerialMaxLength = 20
+
+const defaultDeviceWaitInterval = 500 * time.Millisecond
+
+var ErrDeviceNotFound = errors.New("device not found")
+
+// DeviceResolver finds the block devices by their serials using sysfs and udev data.
+type DeviceResolver struct {
+	sysRoot      string
+	udevDataRoot string
+	devRoot      string
+}
+
+// NewDeviceResolver returns a resolver reading the given sysfs, udev data and /dev roots,
+// which are /sys, /run/udev/data and /dev on a real node.
+func NewDeviceResolver(sysRoot, udevDataRoot, devRoot string) *DeviceResolver {
+	return &DeviceResolver{
+		sysRoot:      sysRoot,
+		udevDataRoot: udevDataRoot,
+		devRoot:      devRoot,
+	}
+}
+
+// WaitForDevice resolves the device path by the serial until it appears after hotplug or the context is done.
+func (r *DeviceResolver) WaitForDevice(ctx context.Context, serial string) (string, error) {
+	for {
+		devicePath, err := r.Resolve(serial)
+		if err == nil {
+			return devicePath, nil
+		}
+
+		if !errors.Is(err, ErrDeviceNotFound) {
+			return "", err
+		}
+
+		timer := time.NewTimer(defaultDeviceWaitInterval)
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return "", fmt.Errorf("device with serial %s did not appear: %w", serial, ctx.Err())
+		}
+	}
+}
+
+// Resolve returns the path of the block device with the serial. A serial truncated by virtio matches too,
+// but only if no other device has the same truncated serial.
+func (r *DeviceResolver) Resolve(serial string) (string, error) {
+	if serial == "" {
+		return "", errors.New("serial cannot be empty")
+	}
+
+	disks, err := r.ListDisks()
+	if err != nil {
+		return "", err
+	}
+
+	var exact, truncated []string
+	for _, name := range disks {
+		deviceSerial := r.readSerial(name)
+		switch {
+		case deviceSerial == "":
+		case deviceSerial == serial:
+			exact = append(exact, name)
+		case len(deviceSerial) == VirtioSerialMaxLength && strings.HasPrefix(serial, deviceSerial):
+			truncated = append(truncated, name)
+		}
+	}
+
+	matches := exact
+	if len(matches) == 0 {
+		matches = truncated
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("%w: serial %s", ErrDeviceNotFound, serial)
+	case 1:
+		return filepath.Join(r.devRoot, matches[0]), nil
+	default:
+		return "", fmt.Errorf("more than one device has serial %s: %s", serial, strings.Join(matches, ", "))
+	}
+}
+
+// ListDisks returns the names of the disks attached to the machine, skipping the virtual block devices.
+func (r *DeviceResolver) ListDisks() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(r.sysRoot, "block"))
+	if err != nil {
+		return nil, err
+	}
+
+	var disks []string
+	for _, entry := range entries {
+		if !isVirtualDevice(entry.Name()) {
+			disks = append(disks, entry.Name())
+		}
+	}
+
+	return disks, nil
+}
+
+// readSerial returns the serial of the device from udev data, falling back to sysfs attributes.
+func (r *DeviceResolver) readSerial(name string) string {
+	serial := r.readUdevSerial(name)
+	if serial != "" {
+		return serial
+	}
+
+	// virtio-blk.
+	data, err := os.ReadFile(filepath.Join(r.sysRoot, "block", name, "serial"))
+	if err == nil {
+		return strings.TrimSpace(string(data))
+	}
+
+	// SCSI: the unit serial number VPD page has a 4 byte header.
+	data, err = os.ReadFile(filepath.Join(r.sysRoot, "block", name, "device", "vpd_pg80"))
+	if err == nil && len(data) > 4 {
+		return strings.TrimSpace(strings.Trim(string(data[4:]), "\x00"))
+	}
+
+	return ""
+}
+
+func (r *DeviceResolver) readUdevSerial(name string) string {
+	majorMinor, err := os.ReadFile(filepath.Join(r.sysRoot, "block", name, "dev"))
+	if err != nil {
+		return ""
+	}
+
+	f, err := os.Open(filepath.Join(r.udevDataRoot, "b"+strings.TrimSpace(string(majorMinor))))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	var serial string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimPrefix(scanner.Text(), "E:"), "=")
+		if !ok {
+			continue
+		}
+
+		switch key {
+		case "ID_SERIAL_SHORT":
+			return value
+		case "ID_SERIAL":
+			serial = value
+		}
+	}
+
+	return serial
+}
+
+func isVirtualDevice(name string) bool {
+	for _, prefix := range []string{"loop", "ram", "dm-", "zram", "nbd", "sr"} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,288 @@
+package mounter
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeDevice is a block device in the fake sysfs tree.
+type fakeDevice struct {
+	name string
+	// dev is a major:minor of the device, the udev data is looked up by.
+	dev string
+	// serial is a sysfs serial attribute of virtio-blk.
+	serial string
+	// vpdSerial is a serial in the unit serial number VPD page of SCSI.
+	vpdSerial string
+	// udevData is the content of the udev data file.
+	udevData string
+}
+
+// newFakeResolver returns a resolver reading the fake sysfs, udev data and /dev trees of the devices.
+func newFakeResolver(t *testing.T, devices ...fakeDevice) *DeviceResolver {
+	t.Helper()
+
+	root := t.TempDir()
+	sysRoot := filepath.Join(root, "sys")
+	udevDataRoot := filepath.Join(root, "udev")
+
+	for _, dir := range []string{filepath.Join(sysRoot, "block"), udevDataRoot} {
+		err := os.MkdirAll(dir, 0o755)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, device := range devices {
+		addFakeDevice(t, sysRoot, udevDataRoot, device)
+	}
+
+	return NewDeviceResolver(sysRoot, udevDataRoot, "/dev")
+}
+
+func addFakeDevice(t *testing.T, sysRoot, udevDataRoot string, device fakeDevice) {
+	t.Helper()
+
+	deviceDir := filepath.Join(sysRoot, "block", device.name)
+
+	files := map[string]string{}
+	if device.dev != "" {
+		files[filepath.Join(deviceDir, "dev")] = device.dev + "\n"
+	}
+	if device.serial != "" {
+		files[filepath.Join(deviceDir, "serial")] = device.serial + "\n"
+	}
+	if device.vpdSerial != "" {
+		files[filepath.Join(deviceDir, "device", "vpd_pg80")] = "\x00\x80\x00" + string(rune(len(device.vpdSerial))) + device.vpdSerial
+	}
+	if device.udevData != "" {
+		files[filepath.Join(udevDataRoot, "b"+device.dev)] = device.udevData
+	}
+
+	err := os.MkdirAll(deviceDir, 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, content := range files {
+		err = os.MkdirAll(filepath.Dir(path), 0o755)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = os.WriteFile(path, []byte(content), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDeviceResolverResolve(t *testing.T) {
+	const serial = "pvc-0f8a1c2e-3b4d-4e5f-8a9b-0c1d2e3f4a5b"
+
+	tests := []struct {
+		name     string
+		devices  []fakeDevice
+		serial   string
+		expected string
+		// err expects an error other than ErrDeviceNotFound.
+		err bool
+		// errNotFound expects ErrDeviceNotFound.
+		errNotFound bool
+	}{
+		{
+			name: "exact serial",
+			devices: []fakeDevice{
+				{name: "vda", dev: "252:0", serial: "boot"},
+				{name: "vdb", dev: "252:16", serial: serial},
+			},
+			serial:   serial,
+			expected: "/dev/vdb",
+		},
+		{
+			name: "truncated serial",
+			devices: []fakeDevice{
+				{name: "vda", dev: "252:0", serial: "boot"},
+				{name: "vdb", dev: "252:16", serial: serial[:VirtioSerialMaxLength]},
+			},
+			serial:   serial,
+			expected: "/dev/vdb",
+		},
+		{
+			name: "exact serial preferred to truncated one",
+			devices: []fakeDevice{
+				{name: "vdb", dev: "252:16", serial: "pvc-0f8a1c2e-3b4d-4e"},
+				{name: "vdc", dev: "252:32", serial: "pvc-0f8a1c2e-3b4d-4e5f"},
+			},
+			serial:   "pvc-0f8a1c2e-3b4d-4e5f",
+			expected: "/dev/vdc",
+		},
+		{
+			name: "truncated serial collision",
+			devices: []fakeDevice{
+				{name: "vdb", dev: "252:16", serial: serial[:VirtioSerialMaxLength]},
+				{name: "vdc", dev: "252:32", serial: serial[:VirtioSerialMaxLength]},
+			},
+			serial: serial,
+			err:    true,
+		},
+		{
+			name: "shorter serial is not truncated",
+			devices: []fakeDevice{
+				{name: "vdb", dev: "252:16", serial: serial[:VirtioSerialMaxLength-1]},
+			},
+			serial:      serial,
+			errNotFound: true,
+		},
+		{
+			name: "udev short serial preferred to sysfs serial",
+			devices: []fakeDevice{
+				{name: "vdb", dev: "252:16", serial: "stale", udevData: "S:disk/by-id/virtio-x\nE:ID_SERIAL=QEMU_disk_" + serial + "\nE:ID_SERIAL_SHORT=" + serial + "\n"},
+			},
+			serial:   serial,
+			expected: "/dev/vdb",
+		},
+		{
+			name: "udev serial without short serial",
+			devices: []fakeDevice{
+				{name: "vdb", dev: "252:16", udevData: "E:ID_SERIAL=" + serial + "\n"},
+			},
+			serial:   serial,
+			expected: "/dev/vdb",
+		},
+		{
+			name: "sysfs serial without udev data",
+			devices: []fakeDevice{
+				{name: "vdb", dev: "252:16", serial: serial},
+			},
+			serial:   serial,
+			expected: "/dev/vdb",
+		},
+		{
+			name: "sysfs serial with udev data without serial",
+			devices: []fakeDevice{
+				{name: "vdb", dev: "252:16", serial: serial, udevData: "E:DEVTYPE=disk\n"},
+			},
+			serial:   serial,
+			expected: "/dev/vdb",
+		},
+		{
+			name: "vpd_pg80 serial",
+			devices: []fakeDevice{
+				{name: "sda", dev: "8:0", vpdSerial: serial},
+			},
+			serial:   serial,
+			expected: "/dev/sda",
+		},
+		{
+			name: "virtual devices skipped",
+			devices: []fakeDevice{
+				{name: "loop0", dev: "7:0", serial: serial},
+				{name: "dm-0", dev: "253:0", serial: serial},
+			},
+			serial:      serial,
+			errNotFound: true,
+		},
+		{
+			name: "not found",
+			devices: []fakeDevice{
+				{name: "vda", dev: "252:0", serial: "boot"},
+				{name: "vdb", dev: "252:16"},
+			},
+			serial:      serial,
+			errNotFound: true,
+		},
+		{
+			name:    "empty serial",
+			devices: []fakeDevice{{name: "vda", dev: "252:0"}},
+			serial:  "",
+			err:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := newFakeResolver(t, tt.devices...).Resolve(tt.serial)
+
+			switch {
+			case tt.errNotFound:
+				if !errors.Is(err, ErrDeviceNotFound) {
+					t.Fatalf("expected device not found, got %q, %v", path, err)
+				}
+			case tt.err:
+				if err == nil || errors.Is(err, ErrDeviceNotFound) {
+					t.Fatalf("expected an error other than device not found, got %q, %v", path, err)
+				}
+			case err != nil:
+				t.Fatal(err)
+			case path != tt.expected:
+				t.Fatalf("expected %s, got %s", tt.expected, path)
+			}
+		})
+	}
+}
+
+func TestDeviceResolverListDisks(t *testing.T) {
+	resolver := newFakeResolver(t,
+		fakeDevice{name: "vda", dev: "252:0"},
+		fakeDevice{name: "loop0", dev: "7:0"},
+		fakeDevice{name: "sr0", dev: "11:0"},
+		fakeDevice{name: "sda", dev: "8:0"},
+	)
+
+	disks, err := resolver.ListDisks()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(disks) != 2 || disks[0] != "sda" || disks[1] != "vda" {
+		t.Fatalf("expected sda and vda, got %v", disks)
+	}
+}
+
+func TestDeviceResolverWaitForDevice(t *testing.T) {
+	const serial = "pvc-hotplugged"
+
+	resolver := newFakeResolver(t, fakeDevice{name: "vda", dev: "252:0", serial: "boot"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	type result struct {
+		path string
+		err  error
+	}
+
+	results := make(chan result, 1)
+	go func() {
+		path, err := resolver.WaitForDevice(ctx, serial)
+		results <- result{path: path, err: err}
+	}()
+
+	// The device is hotplugged while waiting.
+	time.Sleep(100 * time.Millisecond)
+	addFakeDevice(t, resolver.sysRoot, resolver.udevDataRoot, fakeDevice{name: "vdb", dev: "252:16", serial: serial})
+
+	res := <-results
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+
+	if res.path != "/dev/vdb" {
+		t.Fatalf("expected /dev/vdb, got %s", res.path)
+	}
+}
+
+func TestDeviceResolverWaitForDeviceTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err := newFakeResolver(t).WaitForDevice(ctx, "pvc-missing")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
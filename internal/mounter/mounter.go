@@ -1,15 +1,18 @@
 package mounter
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"log/slog"
 	"os"
-	"path/filepath"
-	"strings"
+	"time"
 
+	"go.opentelemetry.io/otel/attribute"
 	mu "k8s.io/mount-utils"
 	utilexec "k8s.io/utils/exec"
+
+	"github.com/deckhouse/dvp-csi-driver/internal/metrics"
+	"github.com/deckhouse/dvp-csi-driver/internal/tracing"
 )
 
 /* DEPS:
@@ -22,9 +25,24 @@ mkfs.ext4 - from e2fsprogs
 mkfs.xfs - from xfsprogs
 */
 
+// defaultDeviceWaitTimeout is a time to wait for the device to appear after hotplug.
+const defaultDeviceWaitTimeout = time.Minute
+
+// Operations the durations are recorded for.
+const (
+	operationFormatAndMount = "format_and_mount"
+	operationBindMount      = "bind_mount"
+	operationUnmount        = "unmount"
+	operationResize         = "resize"
+	operationWaitDevice     = "wait_device"
+)
+
 type Mounter struct {
-	logger *slog.Logger
-	mutils mu.SafeFormatAndMount
+	logger  *slog.Logger
+	mutils  mu.SafeFormatAndMount
+	devices *DeviceResolver
+	// mountInfoPath is a path of the mountinfo of the mount namespace.
+	mountInfoPath string
 }
 
 // New returns a new mounter instance.
@@ -35,10 +53,22 @@ func New(logger *slog.Logger) *Mounter {
 			Interface: mu.New("/bin/mount"),
 			Exec:      utilexec.New(),
 		},
+		devices:       NewDeviceResolver("/sys", "/run/udev/data", "/dev"),
+		mountInfoPath: "/proc/self/mountinfo",
 	}
 }
 
-func (m *Mounter) MountFileSystem(source, target, fsType string, opts ...string) error {
+// IsFileSystemSupported reports whether the file system type can be mounted. An empty type means the default one.
+func IsFileSystemSupported(fsType string) bool {
+	switch fsType {
+	case "ext4", "xfs", "":
+		return true
+	default:
+		return false
+	}
+}
+
+func (m *Mounter) MountFileSystem(ctx context.Context, source, target, fsType string, opts ...string) error {
 	switch fsType {
 	case "ext4", "xfs":
 	case "":
@@ -62,12 +92,20 @@ func (m *Mounter) MountFileSystem(source, target, fsType string, opts ...string)
 		return fmt.Errorf("could not create target directory %s: %w", target, err)
 	}
 
-	_, err = m.mutils.IsMountPoint(target)
+	mounted, err := m.mutils.IsMountPoint(target)
 	if err != nil {
 		return fmt.Errorf("unable to determine mount status of %s %w", target, err)
 	}
 
+	if mounted {
+		m.logger.Debug("Target is already mounted", "target", target)
+		return nil
+	}
+
+	// The file system is checked before mount and created only if the device has none.
+	done := startOperation(ctx, operationFormatAndMount, attribute.String("mount.source", source), attribute.String("mount.target", target))
 	err = m.mutils.FormatAndMount(source, target, fsType, opts)
+	done(err)
 	if err != nil {
 		return fmt.Errorf("failed to FormatAndMount : %w", err)
 	}
@@ -75,7 +113,18 @@ func (m *Mounter) MountFileSystem(source, target, fsType string, opts ...string)
 	return nil
 }
 
-func (m *Mounter) MountBlockDevice(source, target string, opts ...string) error {
+// ReadOnlyMountOptions returns the options to mount the file system without any write to the device,
+// including the journal recovery that the plain "ro" option still does.
+func ReadOnlyMountOptions(fsType string) []string {
+	switch fsType {
+	case "xfs":
+		return []string{"ro", "norecovery"}
+	default:
+		return []string{"ro", "noload"}
+	}
+}
+
+func (m *Mounter) MountBlockDevice(ctx context.Context, source, target string, opts ...string) error {
 	info, err := os.Stat(source)
 	if err != nil {
 		return fmt.Errorf("failed to stat source device: %w", err)
@@ -94,7 +143,33 @@ func (m *Mounter) MountBlockDevice(source, target string, opts ...string) error
 		_ = f.Close()
 	}
 
+	return m.bindMount(ctx, source, target, opts...)
+}
+
+// MountDirectory bind-mounts the source directory, e.g. a staging path, to the target directory.
+func (m *Mounter) MountDirectory(ctx context.Context, source, target string, opts ...string) error {
+	err := os.MkdirAll(target, os.FileMode(0o755))
+	if err != nil {
+		return fmt.Errorf("could not create target directory %s: %w", target, err)
+	}
+
+	return m.bindMount(ctx, source, target, opts...)
+}
+
+func (m *Mounter) bindMount(ctx context.Context, source, target string, opts ...string) error {
+	mounted, err := m.mutils.IsMountPoint(target)
+	if err != nil {
+		return fmt.Errorf("unable to determine mount status of %s %w", target, err)
+	}
+
+	if mounted {
+		m.logger.Debug("Target is already mounted", "target", target)
+		return nil
+	}
+
+	done := startOperation(ctx, operationBindMount, attribute.String("mount.source", source), attribute.String("mount.target", target))
 	err = m.mutils.Mount(source, target, "", append(opts, "bind"))
+	done(err)
 	if err != nil {
 		return err
 	}
@@ -102,22 +177,27 @@ func (m *Mounter) MountBlockDevice(source, target string, opts ...string) error
 	return nil
 }
 
-func (m *Mounter) Unmount(target string) error {
-	err := m.mutils.Unmount(target)
+// Unmount unmounts the target, if mounted, and removes it.
+func (m *Mounter) Unmount(ctx context.Context, target string) error {
+	done := startOperation(ctx, operationUnmount, attribute.String("mount.target", target))
+	err := mu.CleanupMountPoint(target, m.mutils.Interface, true)
+	done(err)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to clean up mount point %s: %w", target, err)
 	}
 
 	return nil
 }
 
-func (m *Mounter) ResizeFS(mountTarget string) error {
+func (m *Mounter) ResizeFS(ctx context.Context, mountTarget string) error {
 	devicePath, _, err := mu.GetDeviceNameFromMount(m.mutils.Interface, mountTarget)
 	if err != nil {
 		return fmt.Errorf("failed to find the device mounted at %s: %w", mountTarget, err)
 	}
 
+	done := startOperation(ctx, operationResize, attribute.String("mount.source", devicePath), attribute.String("mount.target", mountTarget))
 	_, err = mu.NewResizeFs(m.mutils.Exec).Resize(devicePath, mountTarget)
+	done(err)
 	if err != nil {
 		return fmt.Errorf("failed to resize filesystem %s on device %s: %w", mountTarget, devicePath, err)
 	}
@@ -125,30 +205,44 @@ func (m *Mounter) ResizeFS(mountTarget string) error {
 	return nil
 }
 
-const devicesByIDPath = "/dev/disk/by-id/"
+// GetBlockDevicePath returns the path of the block device with the serial, waiting for it to appear.
+func (m *Mounter) GetBlockDevicePath(ctx context.Context, serial string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultDeviceWaitTimeout)
+	defer cancel()
 
-func (m *Mounter) GetBlockDevicePathByID(id string) (string, error) {
-	symlinks, err := os.ReadDir(devicesByIDPath)
-	if err != nil {
-		return "", err
-	}
+	done := startOperation(ctx, operationWaitDevice, attribute.String("device.serial", serial))
+	path, err := m.devices.WaitForDevice(ctx, serial)
+	done(err)
 
-	var symlinkName string
-	for _, symlink := range symlinks {
-		if strings.HasSuffix(symlink.Name(), id) {
-			symlinkName = symlink.Name()
-			break
-		}
-	}
+	return path, err
+}
 
-	if symlinkName == "" {
-		return "", errors.New("symlink not found")
+// startOperation starts the span of the node operation. The returned function ends it and records the duration.
+func startOperation(ctx context.Context, operation string, attrs ...attribute.KeyValue) func(err error) {
+	start := time.Now()
+	_, span := tracing.Start(ctx, "mounter."+operation, attrs...)
+
+	return func(err error) {
+		metrics.ObserveMountOperation(operation, start, err)
+		tracing.End(span, err)
 	}
+}
 
-	blockDevicePath, err := filepath.EvalSymlinks(devicesByIDPath + symlinkName)
+// CountDisks returns the number of the disks attached to the machine, except the ones the excluded function
+// reports by serial. The disks without a readable serial are counted.
+func (m *Mounter) CountDisks(excluded func(serial string) bool) (int, error) {
+	disks, err := m.devices.ListDisks()
 	if err != nil {
-		return "", err
+		return 0, err
+	}
+
+	var count int
+	for _, name := range disks {
+		serial := m.devices.readSerial(name)
+		if serial == "" || !excluded(serial) {
+			count++
+		}
 	}
 
-	return blockDevicePath, nil
+	return count, nil
 }
@@ -0,0 +1,31 @@
+package mounter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCountDisks(t *testing.T) {
+	m := &Mounter{
+		devices: newFakeResolver(t,
+			fakeDevice{name: "vda", dev: "252:0", serial: "boot"},
+			fakeDevice{name: "vdb", dev: "252:16", serial: "cloud-init"},
+			fakeDevice{name: "vdc", dev: "252:32", serial: "pvc-1"},
+			fakeDevice{name: "vdd", dev: "252:48", serial: "pvc-2"},
+			fakeDevice{name: "vde", dev: "252:64"},
+			fakeDevice{name: "loop0", dev: "7:0", serial: "pvc-3"},
+		),
+	}
+
+	count, err := m.CountDisks(func(serial string) bool {
+		return strings.HasPrefix(serial, "pvc-")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The boot, cloud-init and the disk without a serial.
+	if count != 3 {
+		t.Fatalf("expected 3 disks, got %d", count)
+	}
+}
@@ -0,0 +1,40 @@
+package mounter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// requiredTools are the tools the node formats and mounts the volumes with.
+var requiredTools = []string{"mkfs.ext4", "mkfs.xfs", "blkid"}
+
+// CheckTools checks that the tools to format and mount the volumes are installed.
+func (m *Mounter) CheckTools(_ context.Context) error {
+	for _, tool := range requiredTools {
+		_, err := exec.LookPath(tool)
+		if err != nil {
+			return fmt.Errorf("%s not found: %w", tool, err)
+		}
+	}
+
+	return nil
+}
+
+// CheckDevices checks that the sysfs block devices and the udev data the devices are resolved by are available on the node.
+func (m *Mounter) CheckDevices(_ context.Context) error {
+	for _, dir := range []string{filepath.Join(m.devices.sysRoot, "block"), m.devices.udevDataRoot} {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return fmt.Errorf("failed to stat %s: %w", dir, err)
+		}
+
+		if !info.IsDir() {
+			return fmt.Errorf("%s is not a directory", dir)
+		}
+	}
+
+	return nil
+}
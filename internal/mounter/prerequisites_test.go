@@ -0,0 +1,26 @@
+package mounter
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestCheckDevices(t *testing.T) {
+	m := &Mounter{devices: newFakeResolver(t)}
+
+	err := m.CheckDevices(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.RemoveAll(m.devices.udevDataRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.CheckDevices(context.Background())
+	if err == nil {
+		t.Fatal("expected an error without the udev data")
+	}
+}
@@ -0,0 +1,171 @@
+package mounter
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+	mu "k8s.io/mount-utils"
+)
+
+type VolumeStats struct {
+	TotalBytes     int64
+	AvailableBytes int64
+	UsedBytes      int64
+
+	TotalInodes     int64
+	AvailableInodes int64
+	UsedInodes      int64
+}
+
+// VolumeHealth is a result of the volume check. The message describes the problem if the volume is abnormal.
+type VolumeHealth struct {
+	Abnormal bool
+	Message  string
+}
+
+// IsBlockDevice reports whether the path is a block device or a file the device is bind-mounted to.
+func (m *Mounter) IsBlockDevice(path string) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+
+	return info.Mode()&os.ModeDevice == os.ModeDevice, nil
+}
+
+// GetFileSystemStats returns the byte and inode usage of the file system mounted to the path.
+func (m *Mounter) GetFileSystemStats(path string) (*VolumeStats, error) {
+	var statfs unix.Statfs_t
+	err := unix.Statfs(path, &statfs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to statfs %s: %w", path, err)
+	}
+
+	blockSize := int64(statfs.Bsize)
+
+	return &VolumeStats{
+		TotalBytes:      int64(statfs.Blocks) * blockSize,
+		AvailableBytes:  int64(statfs.Bavail) * blockSize,
+		UsedBytes:       int64(statfs.Blocks-statfs.Bfree) * blockSize,
+		TotalInodes:     int64(statfs.Files),
+		AvailableInodes: int64(statfs.Ffree),
+		UsedInodes:      int64(statfs.Files - statfs.Ffree),
+	}, nil
+}
+
+// GetBlockDeviceSize returns the size of the block device at the path.
+func (m *Mounter) GetBlockDeviceSize(path string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get size of block device %s: %w", path, err)
+	}
+
+	return size, nil
+}
+
+// CheckFileSystemHealth checks the device of the file system mounted to the path is present and readable,
+// the file system is not remounted read-only and has no errors recorded.
+func (m *Mounter) CheckFileSystemHealth(path string) VolumeHealth {
+	mountInfos, err := mu.ParseMountInfo(m.mountInfoPath)
+	if err != nil {
+		return VolumeHealth{Abnormal: true, Message: fmt.Sprintf("failed to list mount points: %s", err)}
+	}
+
+	var mountInfo *mu.MountInfo
+	for i := range mountInfos {
+		if mountInfos[i].MountPoint == path {
+			mountInfo = &mountInfos[i]
+		}
+	}
+
+	if mountInfo == nil {
+		return VolumeHealth{Abnormal: true, Message: fmt.Sprintf("nothing is mounted to %s", path)}
+	}
+
+	health := m.checkDevice(mountInfo.Source)
+	if health.Abnormal {
+		return health
+	}
+
+	// The file system mounted read-only on request, such as a shared one, is read-only per mount as well.
+	// The one remounted read-only by the kernel, e.g. on errors, is read-only per superblock only.
+	if hasReadOnlyOption(mountInfo.SuperOptions) && !hasReadOnlyOption(mountInfo.MountOptions) {
+		return VolumeHealth{Abnormal: true, Message: "file system is remounted read-only"}
+	}
+
+	if mountInfo.FsType == "ext4" {
+		errorsCount, err := readExt4ErrorsCount(mountInfo.Source)
+		if err != nil {
+			m.logger.Debug("Failed to read ext4 errors count", "device", mountInfo.Source, "err", err)
+		} else if errorsCount > 0 {
+			return VolumeHealth{Abnormal: true, Message: fmt.Sprintf("file system has %d errors", errorsCount)}
+		}
+	}
+
+	return VolumeHealth{Message: "volume is healthy"}
+}
+
+func hasReadOnlyOption(opts []string) bool {
+	for _, opt := range opts {
+		if opt == "ro" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CheckBlockDeviceHealth checks the block device at the path is present and readable.
+func (m *Mounter) CheckBlockDeviceHealth(path string) VolumeHealth {
+	return m.checkDevice(path)
+}
+
+func (m *Mounter) checkDevice(path string) VolumeHealth {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) || errors.Is(err, unix.ENXIO) || errors.Is(err, unix.ENODEV) {
+			return VolumeHealth{Abnormal: true, Message: fmt.Sprintf("device %s is missing", path)}
+		}
+
+		return VolumeHealth{Abnormal: true, Message: fmt.Sprintf("failed to open device %s: %s", path, err)}
+	}
+	defer f.Close()
+
+	buf := make([]byte, 4096)
+	_, err = f.Read(buf)
+	if err != nil && !errors.Is(err, io.EOF) {
+		if errors.Is(err, unix.EIO) {
+			return VolumeHealth{Abnormal: true, Message: fmt.Sprintf("device %s has I/O errors", path)}
+		}
+
+		return VolumeHealth{Abnormal: true, Message: fmt.Sprintf("failed to read device %s: %s", path, err)}
+	}
+
+	return VolumeHealth{Message: "volume is healthy"}
+}
+
+func readExt4ErrorsCount(device string) (int, error) {
+	device, err := filepath.EvalSymlinks(device)
+	if err != nil {
+		return 0, err
+	}
+
+	data, err := os.ReadFile(filepath.Join("/sys/fs/ext4", filepath.Base(device), "errors_count"))
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
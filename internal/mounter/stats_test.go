@@ -0,0 +1,79 @@
+package mounter
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckFileSystemHealth(t *testing.T) {
+	const target = "/var/lib/kubelet/plugins/kubernetes.io/csi/virtualization.csi.driver.io/0a1b/globalmount"
+
+	tests := []struct {
+		name         string
+		mountOptions string
+		superOptions string
+		target       string
+		abnormal     bool
+	}{
+		{
+			name:         "read-write",
+			mountOptions: "rw,relatime",
+			superOptions: "rw",
+			target:       target,
+		},
+		{
+			name:         "read-only on request",
+			mountOptions: "ro,relatime",
+			superOptions: "ro,norecovery",
+			target:       target,
+		},
+		{
+			name:         "remounted read-only",
+			mountOptions: "rw,relatime",
+			superOptions: "ro",
+			target:       target,
+			abnormal:     true,
+		},
+		{
+			name:         "not mounted",
+			mountOptions: "rw,relatime",
+			superOptions: "rw",
+			target:       "/var/lib/kubelet/other",
+			abnormal:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			// A regular file stands for the readable device.
+			device := filepath.Join(dir, "device")
+			err := os.WriteFile(device, []byte("data"), 0o644)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			mountInfo := fmt.Sprintf("29 1 252:0 / / rw,relatime shared:1 - ext4 /dev/vda1 rw\n"+
+				"731 29 252:16 / %s %s shared:400 - xfs %s %s\n", tt.target, tt.mountOptions, device, tt.superOptions)
+
+			m := &Mounter{
+				logger:        slog.Default(),
+				mountInfoPath: filepath.Join(dir, "mountinfo"),
+			}
+
+			err = os.WriteFile(m.mountInfoPath, []byte(mountInfo), 0o644)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			health := m.CheckFileSystemHealth(target)
+			if health.Abnormal != tt.abnormal {
+				t.Fatalf("expected abnormal %t, got %+v", tt.abnormal, health)
+			}
+		})
+	}
+}
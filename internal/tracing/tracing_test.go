@@ -0,0 +1,40 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/deckhouse/dvp-csi-driver/internal/tracing"
+	"github.com/deckhouse/dvp-csi-driver/internal/tracing/tracingtest"
+)
+
+func TestStartEnd(t *testing.T) {
+	spans := tracingtest.Record(t)
+
+	ctx, parent := tracing.Start(context.Background(), "parent")
+	_, child := tracing.Start(ctx, "child")
+	tracing.End(child, errors.New("failed"))
+	tracing.End(parent, nil)
+
+	recorded := spans()
+	if len(recorded) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(recorded))
+	}
+
+	childSpan, parentSpan := recorded[0], recorded[1]
+
+	if childSpan.Parent.SpanID() != parentSpan.SpanContext.SpanID() {
+		t.Fatal("expected the child span to be a child of the parent one")
+	}
+
+	if childSpan.Status.Code != codes.Error || childSpan.Status.Description != "failed" || len(childSpan.Events) != 1 {
+		t.Fatalf("expected the child span to record the error, got %+v", childSpan.Status)
+	}
+
+	if parentSpan.Status.Code != codes.Unset {
+		t.Fatalf("expected the parent span to have no error, got %+v", parentSpan.Status)
+	}
+}
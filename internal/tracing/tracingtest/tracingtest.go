@@ -0,0 +1,62 @@
+// Package tracingtest provides the utilities to check the spans in the tests.
+package tracingtest
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/deckhouse/dvp-csi-driver/internal/tracing"
+)
+
+// Record sets the global tracer provider recording the spans in memory until the test ends.
+// The returned function flushes and returns the recorded spans.
+func Record(t *testing.T) func() tracetest.SpanStubs {
+	t.Helper()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider, err := tracing.NewTracerProvider(exporter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+	})
+
+	return func() tracetest.SpanStubs {
+		err := provider.ForceFlush(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return exporter.GetSpans()
+	}
+}
+
+// FindSpan returns the first span with the name, failing the test if there is none.
+func FindSpan(t *testing.T, spans tracetest.SpanStubs, name string) tracetest.SpanStub {
+	t.Helper()
+
+	for _, span := range spans {
+		if span.Name == name {
+			return span
+		}
+	}
+
+	t.Fatalf("span %s not found", name)
+	return tracetest.SpanStub{}
+}
+
+// ExpectChild fails the test unless the child span is a child of the parent one.
+func ExpectChild(t *testing.T, child, parent tracetest.SpanStub) {
+	t.Helper()
+
+	if child.Parent.SpanID() != parent.SpanContext.SpanID() {
+		t.Fatalf("expected span %s to be a child of %s", child.Name, parent.Name)
+	}
+}